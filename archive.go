@@ -0,0 +1,138 @@
+package sourcegit
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/apuigsech/seekret/models"
+)
+
+func isArchiveSource(source string) bool {
+	if !strings.HasSuffix(source, ".tar") && !strings.HasSuffix(source, ".tar.gz") && !strings.HasSuffix(source, ".tgz") {
+		return false
+	}
+
+	info, err := os.Stat(source)
+	return err == nil && !info.IsDir()
+}
+
+// objectsFromArchive emits the regular files of a "git archive" or GitHub
+// source tarball as file-content objects, as they would be found at the
+// archived commit. The archive is read as a stream and never extracted.
+//
+// The commit id git archive stores in the pax global header is used as
+// "commit" metadata, and the "<owner>-<repo>-<commit>" top-level directory
+// of GitHub tarballs is stripped from the names so paths match those of the
+// repository. Other top-level directories are left as they are: they can be
+// a directory of the repository.
+func objectsFromArchive(source string, collector *objectCollector) error {
+	fh, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var r io.Reader = fh
+	if !strings.HasSuffix(source, ".tar") {
+		gz, err := gzip.NewReader(fh)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var objectList []models.Object
+	var commit string
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading archive %s: %v", source, err)
+		}
+
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			if comment, ok := hdr.PAXRecords["comment"]; ok {
+				commit = strings.TrimSpace(comment)
+			}
+			continue
+		}
+
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("reading %s from archive %s: %v", hdr.Name, source, err)
+		}
+
+		o := models.NewObject(strings.TrimPrefix(hdr.Name, "./"), Type, "file-content", content)
+		o.SetMetadata("status", "archive", models.MetadataAttributes{})
+		// Same key as the blob would have in the repository.
+		setUniqId(o, gitBlobId(content))
+		objectList = append(objectList, *o)
+	}
+
+	prefix := archivePrefix(objectList, commit)
+	for i := range objectList {
+		objectList[i].Name = strings.TrimPrefix(objectList[i].Name, prefix)
+		if commit != "" {
+			objectList[i].SetMetadata("commit", commit, models.MetadataAttributes{})
+		}
+	}
+
+	return collector.add(objectList...)
+}
+
+// githubArchiveRoot matches the top-level directory of GitHub tarballs,
+// "<owner>-<repo>-<abbreviated commit>/".
+var githubArchiveRoot = regexp.MustCompile(`^[A-Za-z0-9_.-]+-([0-9a-f]{7,40})/$`)
+
+// archivePrefix returns the top-level directory of a GitHub tarball shared
+// by every file of the archive, if any. When the commit of the archive is
+// known, the directory must name it.
+func archivePrefix(objectList []models.Object, commit string) string {
+	if len(objectList) == 0 {
+		return ""
+	}
+
+	i := strings.Index(objectList[0].Name, "/")
+	if i < 0 {
+		return ""
+	}
+	prefix := objectList[0].Name[:i+1]
+
+	m := githubArchiveRoot.FindStringSubmatch(prefix)
+	if m == nil || commit != "" && !strings.HasPrefix(commit, m[1]) {
+		return ""
+	}
+
+	for _, o := range objectList {
+		if !strings.HasPrefix(o.Name, prefix) {
+			return ""
+		}
+	}
+
+	return prefix
+}
+
+// gitBlobId computes the SHA-1 object id git gives to content.
+func gitBlobId(content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
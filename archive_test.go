@@ -0,0 +1,38 @@
+package sourcegit
+
+import (
+	"testing"
+
+	"github.com/apuigsech/seekret/models"
+)
+
+func TestArchivePrefix(t *testing.T) {
+	const commit = "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"
+
+	tests := []struct {
+		name   string
+		files  []string
+		commit string
+		want   string
+	}{
+		{"github", []string{"octocat-Hello-World-7fd1a60/README", "octocat-Hello-World-7fd1a60/src/main.go"}, commit, "octocat-Hello-World-7fd1a60/"},
+		{"github without commit", []string{"octocat-Hello-World-7fd1a60/README"}, "", "octocat-Hello-World-7fd1a60/"},
+		{"github of another commit", []string{"octocat-Hello-World-1234567/README"}, commit, ""},
+		{"directory of the repository", []string{"src/main.go", "src/util.go"}, commit, ""},
+		{"hyphenated directory of the repository", []string{"my-lib/main.go"}, "", ""},
+		{"several top-level entries", []string{"o-r-7fd1a60/README", "o-r-7fd1a60.txt"}, commit, ""},
+		{"top-level file", []string{"README"}, commit, ""},
+		{"empty archive", nil, commit, ""},
+	}
+
+	for _, test := range tests {
+		var objectList []models.Object
+		for _, name := range test.files {
+			objectList = append(objectList, *models.NewObject(name, Type, "file-content", nil))
+		}
+
+		if got := archivePrefix(objectList, test.commit); got != test.want {
+			t.Errorf("%s: prefix %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+	"strconv"
+)
+
+// annotateAtHead flags every file-content object with whether its blob is
+// still somewhere in the tree of HEAD ("at-head") and whether it is still
+// the content of the same path there ("path-at-head").
+func (c *objectCollector) annotateAtHead(repo *git.Repository) error {
+	blobs, paths, err := headContents(repo)
+	if err != nil {
+		return err
+	}
+
+	for i := range c.objects {
+		o := &c.objects[i]
+		if o.SubType != "file-content" {
+			continue
+		}
+		id, err := o.GetMetadata("uniq-id")
+		if err != nil {
+			continue
+		}
+
+		o.SetMetadata("at-head", strconv.FormatBool(blobs[id]), models.MetadataAttributes{})
+		o.SetMetadata("path-at-head", strconv.FormatBool(paths[o.Name] == id), models.MetadataAttributes{})
+	}
+
+	return nil
+}
+
+// headContents returns the ids of the blobs in the tree of HEAD, and the
+// blob id of every path in it.
+func headContents(repo *git.Repository) (map[string]bool, map[string]string, error) {
+	blobs := make(map[string]bool)
+	paths := make(map[string]string)
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer head.Free()
+
+	commit, err := repo.LookupCommit(head.Target())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer commit.Free()
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tree.Free()
+
+	err = git.WalkTree(tree, func(base string, tentry *git.TreeEntry) int {
+		if tentry.Type == git.ObjectBlob {
+			blobs[tentry.Id.String()] = true
+			paths[base+tentry.Name] = tentry.Id.String()
+		}
+		return 0
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return blobs, paths, nil
+}
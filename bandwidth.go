@@ -0,0 +1,284 @@
+package sourcegit
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// throttle paces transfers to limit bytes per second on average.
+type throttle struct {
+	limit int64
+	start time.Time
+
+	mu    sync.Mutex
+	total int64
+}
+
+func newThrottle(limit int64) *throttle {
+	return &throttle{
+		limit: limit,
+		start: time.Now(),
+	}
+}
+
+// wait accounts n more bytes and blocks until they fit in the limit.
+func (t *throttle) wait(n int64) {
+	t.mu.Lock()
+	t.total += n
+	due := t.start.Add(time.Duration(float64(t.total) / float64(t.limit) * float64(time.Second)))
+	t.mu.Unlock()
+
+	if d := time.Until(due); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// throttleCallbacks paces the transfers of a libgit2 clone or fetch: the
+// transfer progress callback runs in the download loop, so blocking it
+// holds the download back.
+func throttleCallbacks(callbacks *git.RemoteCallbacks, limit int64) {
+	t := newThrottle(limit)
+
+	var received uint
+	callbacks.TransferProgressCallback = func(stats git.TransferProgress) git.CallbackResult {
+		if stats.ReceivedBytes > received {
+			t.wait(int64(stats.ReceivedBytes - received))
+			received = stats.ReceivedBytes
+		}
+		return callbackOk
+	}
+}
+
+// throttledProxy is a local HTTP CONNECT proxy pacing what it receives, for
+// the HTTPS clones of the git CLI. It goes through upstream, the proxy the
+// clone would have used otherwise, if any.
+type throttledProxy struct {
+	listener net.Listener
+	throttle *throttle
+	upstream *url.URL
+}
+
+func newThrottledProxy(limit int64, upstream *url.URL) (*throttledProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	p := &throttledProxy{
+		listener: listener,
+		throttle: newThrottle(limit),
+		upstream: upstream,
+	}
+	go p.serve()
+
+	return p, nil
+}
+
+func (p *throttledProxy) Url() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+func (p *throttledProxy) Close() error {
+	return p.listener.Close()
+}
+
+func (p *throttledProxy) serve() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go p.tunnel(conn)
+	}
+}
+
+func (p *throttledProxy) tunnel(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	req, err := http.ReadRequest(r)
+	if err != nil {
+		return
+	}
+	if req.Method != http.MethodConnect {
+		io.WriteString(conn, "HTTP/1.1 405 Method Not Allowed\r\n\r\n")
+		return
+	}
+
+	upstream, err := p.dial(req.Host)
+	if err != nil {
+		io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return
+	}
+	defer upstream.Close()
+
+	if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		return
+	}
+
+	go func() {
+		io.Copy(upstream, r)
+		upstream.Close()
+	}()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := upstream.Read(buf)
+		if n > 0 {
+			p.throttle.wait(int64(n))
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// dial connects to host, through a CONNECT tunnel of the upstream proxy if
+// any.
+func (p *throttledProxy) dial(host string) (net.Conn, error) {
+	if p.upstream == nil {
+		return net.Dial("tcp", host)
+	}
+
+	addr := p.upstream.Host
+	if p.upstream.Port() == "" {
+		// The default of curl, which the git CLI goes through.
+		addr = net.JoinHostPort(p.upstream.Hostname(), "1080")
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	connect := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: host},
+		Host:   host,
+		Header: make(http.Header),
+	}
+	if user := p.upstream.User; user != nil {
+		password, _ := user.Password()
+		connect.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)))
+	}
+	if err := connect.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// Nothing comes from the remote before the client speaks, so nothing
+	// is left buffered past the response.
+	resp, err := http.ReadResponse(bufio.NewReader(conn), connect)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", addr, host, resp.Status)
+	}
+
+	return conn, nil
+}
+
+// ambientProxy is the proxy the git CLI would clone gitUri through: the
+// http.proxy of the git configuration, else HTTPS_PROXY or ALL_PROXY from
+// the environment, as NO_PROXY allows. Nil without any.
+func ambientProxy(gitUri string) (*url.URL, error) {
+	var proxy string
+	if out, err := gitCommand("", "config", "--get-urlmatch", "http.proxy", gitUri).Output(); err == nil {
+		proxy = strings.TrimSpace(string(out))
+	}
+
+	if proxy == "" {
+		req, err := http.NewRequest(http.MethodGet, gitUri, nil)
+		if err != nil {
+			return nil, err
+		}
+		u, err := http.ProxyFromEnvironment(req)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case u != nil:
+			proxy = u.String()
+		case os.Getenv("ALL_PROXY") != "":
+			proxy = os.Getenv("ALL_PROXY")
+		case os.Getenv("all_proxy") != "":
+			proxy = os.Getenv("all_proxy")
+		default:
+			return nil, nil
+		}
+	}
+
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %v", proxy, err)
+	}
+	if u.Scheme != "http" {
+		return nil, fmt.Errorf("max-bandwidth cannot go through the %s proxy %s", u.Scheme, u.Host)
+	}
+
+	return u, nil
+}
+
+// openGitRepoRemoteThrottled clones an HTTPS remote with the git CLI through
+// a throttledProxy, chained to the ambient proxy, which is dropped from the
+// configuration of the clone once done.
+func openGitRepoRemoteThrottled(gitUri string, opt SourceGitLoadOptions, options ...string) (*git.Repository, error) {
+	upstream, err := ambientProxy(gitUri)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := newThrottledProxy(opt.MaxBandwidth, upstream)
+	if err != nil {
+		return nil, err
+	}
+	defer proxy.Close()
+
+	options = append(options, "-c", "http.proxy="+proxy.Url())
+
+	var repo *git.Repository
+	if opt.HeadOnly {
+		repo, err = openGitRepoRemoteShallow(gitUri, opt, options...)
+	} else {
+		repo, err = openGitRepoRemoteCli(gitUri, opt, options...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := repo.Config()
+	if err != nil {
+		repo.Free()
+		return nil, err
+	}
+	defer config.Free()
+
+	if err := config.Delete("http.proxy"); err != nil {
+		repo.Free()
+		return nil, err
+	}
+
+	return repo, nil
+}
@@ -0,0 +1,68 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+	"time"
+)
+
+// blobLifetimes tracks, for blob-lifetime scans, the oldest and newest
+// commits found to contain every blob.
+type blobLifetimes struct {
+	blobs map[string]*blobLifetime
+}
+
+type blobLifetime struct {
+	first     string
+	firstTime time.Time
+	last      string
+	lastTime  time.Time
+}
+
+// see records that the tree of commit contains the blob.
+func (bl *blobLifetimes) see(blobId string, commit *git.Commit) {
+	if bl.blobs == nil {
+		bl.blobs = make(map[string]*blobLifetime)
+	}
+
+	when := commit.Committer().When
+	l, ok := bl.blobs[blobId]
+	if !ok {
+		bl.blobs[blobId] = &blobLifetime{
+			first:     commit.Id().String(),
+			firstTime: when,
+			last:      commit.Id().String(),
+			lastTime:  when,
+		}
+		return
+	}
+
+	if when.Before(l.firstTime) {
+		l.first, l.firstTime = commit.Id().String(), when
+	}
+	if when.After(l.lastTime) {
+		l.last, l.lastTime = commit.Id().String(), when
+	}
+}
+
+// annotateLifetimes adds to every file-content object the commits where its
+// blob was first and last seen.
+func (c *objectCollector) annotateLifetimes() {
+	for i := range c.objects {
+		o := &c.objects[i]
+		if o.SubType != "file-content" {
+			continue
+		}
+		uniqId, err := o.GetMetadata("uniq-id")
+		if err != nil {
+			continue
+		}
+		l, ok := c.lifetimes.blobs[uniqId]
+		if !ok {
+			continue
+		}
+
+		o.SetMetadata("first-seen", l.first, models.MetadataAttributes{})
+		o.SetMetadata("last-seen", l.last, models.MetadataAttributes{})
+	}
+}
@@ -0,0 +1,48 @@
+package sourcegit
+
+import (
+	"strings"
+
+	"github.com/apuigsech/seekret/models"
+)
+
+func blobSet(ids []string) map[string]bool {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[strings.ToLower(strings.TrimSpace(id))] = true
+	}
+
+	return set
+}
+
+// skippedBlob reports whether the content of blob id must never be loaded
+// (skip-blobs).
+func (c *objectCollector) skippedBlob(id string) bool {
+	return c.skipBlobs[id] && !c.includeBlobs[id]
+}
+
+// includedBlob reports whether blob id must be returned wherever it is
+// reached (include-blobs).
+func (c *objectCollector) includedBlob(id string) bool {
+	return c.includeBlobs[id]
+}
+
+// includedObject reports whether o is the content of a blob in include-blobs,
+// and marks it as such.
+func (c *objectCollector) includedObject(o *models.Object) bool {
+	if len(c.includeBlobs) == 0 {
+		return false
+	}
+
+	id, err := o.GetMetadata("uniq-id")
+	if err != nil || !c.includedBlob(uniqIdOid(id)) {
+		return false
+	}
+	o.SetMetadata("included-blob", "true", models.MetadataAttributes{})
+
+	return true
+}
@@ -0,0 +1,66 @@
+package sourcegit
+
+import (
+	"path"
+	"strings"
+)
+
+// buildConfigNames are the CI configuration files recognized by name.
+var buildConfigNames = map[string]bool{
+	".gitlab-ci.yml":          true,
+	".travis.yml":             true,
+	"azure-pipelines.yml":     true,
+	"bitbucket-pipelines.yml": true,
+	"cloudbuild.yaml":         true,
+	"cloudbuild.yml":          true,
+	"buildspec.yml":           true,
+}
+
+// buildConfigDirs are the directories whose YAML files are CI configuration.
+var buildConfigDirs = []string{".github/workflows/", ".circleci/", ".buildkite/"}
+
+// isBuildConfig reports whether p is a container build or CI configuration
+// file, where secrets often end up.
+func isBuildConfig(p string) bool {
+	switch fileType(p, nil) {
+	case "dockerfile", "docker-compose", "jenkinsfile":
+		return true
+	}
+
+	base := strings.ToLower(path.Base(p))
+	if buildConfigNames[base] || base == "compose.yml" || base == "compose.yaml" {
+		return true
+	}
+
+	if ext := path.Ext(base); ext == ".yml" || ext == ".yaml" {
+		for _, dir := range buildConfigDirs {
+			if strings.HasPrefix(p, dir) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// excludedPath reports whether p is excluded by path-exclude, or by
+// dotfiles-only. Build configuration files and, unless path-exclude-dotfiles
+// is set, dotfiles are never excluded by path-exclude, except for those in
+// an excluded directory (the .eslintrc files of node_modules).
+func (c *objectCollector) excludedPath(p string) bool {
+	if c.opt.DotfilesOnly && !isDotPath(p) {
+		return true
+	}
+	if len(c.opt.PathExclude) == 0 || !matchPathspec(p, c.opt.PathExclude) {
+		return false
+	}
+	if isBuildConfig(p) {
+		return false
+	}
+	if c.opt.PathExcludeDotfiles {
+		return true
+	}
+
+	parent, dot := dotPathParent(p)
+	return !dot || parent != "" && matchPathspec(parent, c.opt.PathExclude)
+}
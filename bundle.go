@@ -0,0 +1,247 @@
+package sourcegit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// bundleRefs are the refs walked when scanning a bundle without an explicit
+// ref selection: everything the bundle carries.
+var bundleRefs = []string{"refs/heads", "refs/tags", "refs/remotes"}
+
+func isBundleSource(source string) bool {
+	if !strings.HasSuffix(source, ".bundle") {
+		return false
+	}
+
+	info, err := os.Stat(source)
+	return err == nil && !info.IsDir()
+}
+
+// openGitRepoBundle indexes the pack of a git bundle into a temporary bare
+// repository and recreates the refs listed in its header.
+func openGitRepoBundle(path string) (*git.Repository, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	r := bufio.NewReader(fh)
+	header, err := readBundleHeader(path, r)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]*git.Oid)
+	for name, id := range header.refs {
+		oid, err := git.NewOid(id)
+		if err != nil {
+			return nil, err
+		}
+		refs[name] = oid
+	}
+	var prerequisites []*git.Oid
+	for _, id := range header.prerequisites {
+		if oid, err := git.NewOid(id); err == nil {
+			prerequisites = append(prerequisites, oid)
+		}
+	}
+
+	tmpdir, err := tempDir("bundle", path)
+	if err != nil {
+		return nil, err
+	}
+
+	opened := false
+	defer func() {
+		if !opened {
+			removeTempDir(tmpdir)
+		}
+	}()
+
+	repo, err := git.InitRepository(tmpdir, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writePack(repo, r); err != nil {
+		repo.Free()
+		return nil, err
+	}
+
+	for name, oid := range refs {
+		if name == "HEAD" {
+			continue
+		}
+		ref, err := repo.References.Create(name, oid, true, "bundle")
+		if err != nil {
+			repo.Free()
+			return nil, err
+		}
+		ref.Free()
+	}
+
+	if err := setBundleHead(repo, refs); err != nil {
+		repo.Free()
+		return nil, err
+	}
+
+	if len(prerequisites) > 0 {
+		if err := writeBundleShallow(repo, prerequisites); err != nil {
+			repo.Free()
+			return nil, err
+		}
+	}
+
+	opened = true
+
+	return repo, nil
+}
+
+// bundleHeader is what the header of a git bundle lists: the refs it
+// carries and the commits it requires, by id.
+type bundleHeader struct {
+	refs          map[string]string
+	prerequisites []string
+}
+
+// readBundleHeader reads the header of the v2 or v3 git bundle at path from
+// r, leaving r at the start of its pack.
+func readBundleHeader(path string, r *bufio.Reader) (*bundleHeader, error) {
+	signature, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	signature = strings.TrimSpace(signature)
+	if signature != "# v2 git bundle" && signature != "# v3 git bundle" {
+		return nil, fmt.Errorf("%s is not a git bundle", path)
+	}
+
+	header := &bundleHeader{refs: make(map[string]string)}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("truncated bundle header in %s: %v", path, err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			break
+		}
+
+		switch {
+		case strings.HasPrefix(line, "@"):
+			// v3 capability, e.g. @object-format=sha1.
+			if strings.HasPrefix(line, "@object-format=") && line != "@object-format=sha1" {
+				return nil, checkObjectFormat(path, strings.TrimPrefix(line, "@object-format="))
+			}
+		case strings.HasPrefix(line, "-"):
+			// "-<id> <comment>".
+			if fields := strings.Fields(line[1:]); len(fields) > 0 {
+				header.prerequisites = append(header.prerequisites, fields[0])
+			}
+		default:
+			fields := strings.SplitN(line, " ", 2)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("invalid bundle header line %q", line)
+			}
+			header.refs[fields[1]] = fields[0]
+		}
+	}
+
+	return header, nil
+}
+
+// writePack indexes a pack stream into the object database of repo.
+func writePack(repo *git.Repository, pack io.Reader) error {
+	odb, err := repo.Odb()
+	if err != nil {
+		return err
+	}
+	defer odb.Free()
+
+	writepack, err := odb.NewWritePack(func(stats git.TransferProgress) git.CallbackResult {
+		return callbackOk
+	})
+	if err != nil {
+		return err
+	}
+	defer writepack.Free()
+
+	if _, err := io.Copy(writepack, pack); err != nil {
+		return err
+	}
+
+	return writepack.Commit()
+}
+
+// setBundleHead points HEAD to the branch the bundle's HEAD matches, or to
+// the bundle's HEAD commit directly, or to any of its branches.
+func setBundleHead(repo *git.Repository, refs map[string]*git.Oid) error {
+	head, ok := refs["HEAD"]
+	for name, oid := range refs {
+		if strings.HasPrefix(name, "refs/heads/") && (!ok || oid.Equal(head)) {
+			return repo.SetHead(name)
+		}
+	}
+
+	if ok {
+		return repo.SetHeadDetached(head)
+	}
+
+	for _, oid := range refs {
+		return repo.SetHeadDetached(oid)
+	}
+
+	return fmt.Errorf("bundle contains no refs")
+}
+
+// writeBundleShallow marks the commits whose parents are prerequisites of an
+// incremental bundle as a shallow boundary, so the walk stops there instead
+// of failing on the missing parents.
+func writeBundleShallow(repo *git.Repository, prerequisites []*git.Oid) error {
+	missing := make(map[string]bool)
+	for _, oid := range prerequisites {
+		missing[oid.String()] = true
+	}
+
+	odb, err := repo.Odb()
+	if err != nil {
+		return err
+	}
+	defer odb.Free()
+
+	var boundary []string
+	err = odb.ForEach(func(oid *git.Oid) error {
+		commit, err := repo.LookupCommit(oid)
+		if err != nil {
+			// Not a commit.
+			return nil
+		}
+		defer commit.Free()
+
+		for i := uint(0); i < commit.ParentCount(); i++ {
+			if missing[commit.ParentId(i).String()] {
+				boundary = append(boundary, oid.String())
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(boundary) == 0 {
+		return nil
+	}
+
+	return ioutil.WriteFile(filepath.Join(repo.Path(), "shallow"), []byte(strings.Join(boundary, "\n")+"\n"), 0644)
+}
@@ -0,0 +1,95 @@
+package sourcegit
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadBundleHeader(t *testing.T) {
+	const (
+		one = "1111111111111111111111111111111111111111"
+		two = "2222222222222222222222222222222222222222"
+	)
+
+	tests := []struct {
+		name              string
+		data              string
+		wantRefs          map[string]string
+		wantPrerequisites []string
+		wantErr           bool
+		// The error wanted, when a specific one.
+		wantErrIs error
+	}{
+		{
+			name:     "v2",
+			data:     "# v2 git bundle\n" + one + " refs/heads/main\n" + two + " HEAD\n\nPACK",
+			wantRefs: map[string]string{"refs/heads/main": one, "HEAD": two},
+		},
+		{
+			name:              "prerequisites",
+			data:              "# v2 git bundle\n-" + one + " parent commit\n-\n" + two + " refs/heads/main\n\nPACK",
+			wantRefs:          map[string]string{"refs/heads/main": two},
+			wantPrerequisites: []string{one},
+		},
+		{
+			name:     "v3",
+			data:     "# v3 git bundle\n@object-format=sha1\n@filter=blob:none\n" + one + " refs/tags/v1\n\nPACK",
+			wantRefs: map[string]string{"refs/tags/v1": one},
+		},
+		{
+			name:      "sha256",
+			data:      "# v3 git bundle\n@object-format=sha256\n" + strings.Repeat("a", 64) + " refs/heads/main\n\nPACK",
+			wantErr:   true,
+			wantErrIs: ErrUnsupportedObjectFormat,
+		},
+		{
+			name:    "not a bundle",
+			data:    "PACK",
+			wantErr: true,
+		},
+		{
+			name:    "unknown version",
+			data:    "# v4 git bundle\n" + one + " refs/heads/main\n\nPACK",
+			wantErr: true,
+		},
+		{
+			name:    "truncated",
+			data:    "# v2 git bundle\n" + one + " refs/heads/main\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid line",
+			data:    "# v2 git bundle\n" + one + "\n\nPACK",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		r := bufio.NewReader(strings.NewReader(test.data))
+		header, err := readBundleHeader("test.bundle", r)
+		if test.wantErr {
+			if err == nil || test.wantErrIs != nil && !errors.Is(err, test.wantErrIs) {
+				t.Errorf("%s: error %v, want %v", test.name, err, test.wantErrIs)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(header.refs, test.wantRefs) {
+			t.Errorf("%s: refs %v, want %v", test.name, header.refs, test.wantRefs)
+		}
+		if !reflect.DeepEqual(header.prerequisites, test.wantPrerequisites) {
+			t.Errorf("%s: prerequisites %v, want %v", test.name, header.prerequisites, test.wantPrerequisites)
+		}
+		if pack, _ := ioutil.ReadAll(r); string(pack) != "PACK" {
+			t.Errorf("%s: pack %q left to read, want %q", test.name, pack, "PACK")
+		}
+	}
+}
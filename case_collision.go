@@ -0,0 +1,36 @@
+package sourcegit
+
+import (
+	"strings"
+
+	"github.com/apuigsech/seekret/models"
+)
+
+// casePaths groups the paths of a tree by their case-folded form. Object
+// names always keep the case the path was committed with; collisions are
+// only reported through metadata.
+type casePaths map[string][]string
+
+func (c casePaths) add(path string) {
+	folded := strings.ToLower(path)
+	c[folded] = append(c[folded], path)
+}
+
+// annotate sets the "case-collision" metadata of every object whose path
+// collides with others, to the comma separated list of those other paths.
+func (c casePaths) annotate(objectList []models.Object) {
+	for i := range objectList {
+		paths := c[strings.ToLower(objectList[i].Name)]
+		if len(paths) < 2 {
+			continue
+		}
+
+		var others []string
+		for _, p := range paths {
+			if p != objectList[i].Name {
+				others = append(others, p)
+			}
+		}
+		objectList[i].SetMetadata("case-collision", strings.Join(others, ","), models.MetadataAttributes{})
+	}
+}
@@ -0,0 +1,95 @@
+package sourcegit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// ErrPathEscape is returned for repository paths that would be written
+// outside of the directory they are checked out to.
+var ErrPathEscape = errors.New("path escapes the checkout directory")
+
+// indexHead fills the index of a remote clone from HEAD, as a checkout
+// would, for its staged files. Remote clones are never checked out: every
+// object is read from the object database, so a hostile tree (a symlink
+// pointing out of the clone followed by files below it, ".git" entries)
+// never reaches the disk.
+func indexHead(repo *git.Repository) error {
+	head, err := repo.Head()
+	if err != nil {
+		if isUnbornBranch(err) || isNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	defer head.Free()
+
+	commit, err := repo.LookupCommit(head.Target())
+	if err != nil {
+		return err
+	}
+	defer commit.Free()
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	defer tree.Free()
+
+	index, err := repo.Index()
+	if err != nil {
+		return err
+	}
+	defer index.Free()
+
+	if err := index.ReadTree(tree); err != nil {
+		return err
+	}
+
+	return index.Write()
+}
+
+// checkoutPath returns where the repository path name is in the working
+// directory root, for anything reading or writing the files of a working
+// directory. It fails with ErrPathEscape for absolute paths, ".." and ".git"
+// components, and paths that are or go through a symbolic link below root,
+// which could point anywhere. Like git, it does not follow a symbolic link
+// even for the last component (.mailmap, untracked files).
+func checkoutPath(root string, name string) (string, error) {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") || strings.HasPrefix(name, "\\") {
+		return "", fmt.Errorf("%s: %v", name, ErrPathEscape)
+	}
+
+	p := root
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' })
+	for i, part := range parts {
+		if part == ".." || part == "." || strings.EqualFold(strings.TrimRight(part, ". "), ".git") {
+			return "", fmt.Errorf("%s: %v", name, ErrPathEscape)
+		}
+		// Alternate data streams and drive letters.
+		if runtime.GOOS == "windows" && strings.Contains(part, ":") {
+			return "", fmt.Errorf("%s: %v", name, ErrPathEscape)
+		}
+
+		p = filepath.Join(p, part)
+
+		info, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 || (i < len(parts)-1 && !info.IsDir()) {
+			return "", fmt.Errorf("%s: %v", name, ErrPathEscape)
+		}
+	}
+
+	return p, nil
+}
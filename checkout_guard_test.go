@@ -0,0 +1,175 @@
+package sourcegit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apuigsech/seekret"
+)
+
+func TestCheckoutPath(t *testing.T) {
+	root, err := ioutil.TempDir("", "sourcegit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	outside, err := ioutil.TempDir("", "sourcegit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+
+	if err := os.Mkdir(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(root, "dir", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		escape bool
+	}{
+		{"file", false},
+		{"dir/file", false},
+		{"new/dir/file", false},
+		{"/etc/passwd", true},
+		{"\\etc\\passwd", true},
+		{"../file", true},
+		{"dir/../../file", true},
+		{"./file", true},
+		{".git/config", true},
+		{".GIT/hooks/post-checkout", true},
+		{"dir/.git./config", true},
+		{"link", true},
+		{"link/file", true},
+		{"dir/link", true},
+		{"file/file", true},
+	} {
+		p, err := checkoutPath(root, tc.name)
+		if tc.escape {
+			if err == nil || !strings.Contains(err.Error(), ErrPathEscape.Error()) {
+				t.Errorf("%s: got %q, %v, want ErrPathEscape", tc.name, p, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		} else if !strings.HasPrefix(p, root+string(filepath.Separator)) {
+			t.Errorf("%s: %s is outside of %s", tc.name, p, root)
+		}
+	}
+}
+
+// TestMaliciousTreeNotFollowed scans a working directory whose committed and
+// untracked symbolic links point to a secret outside of it.
+func TestMaliciousTreeNotFollowed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sourcegit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outside, err := ioutil.TempDir("", "sourcegit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+
+	secret := []byte("outside-of-the-repository\n")
+	if err := ioutil.WriteFile(filepath.Join(outside, "secret"), secret, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testGit(t, dir, "init", "--quiet")
+	if err := os.Symlink(outside, filepath.Join(dir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "README"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testGit(t, dir, "add", ".")
+	testGit(t, dir, "commit", "--quiet", "-m", "initial")
+
+	for name, target := range map[string]string{
+		"untracked":   filepath.Join(outside, "secret"),
+		".mailmap":    filepath.Join(outside, "secret"),
+		"untracked-d": outside,
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	objects, err := SourceTypeGit.LoadObjects(dir, seekret.LoadOptions{
+		"commit-files":     true,
+		"worktree-changes": true,
+		"mailmap":          true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, o := range objects {
+		if bytes.Contains(o.Content, secret) {
+			t.Errorf("%s (%s) holds the content of a file outside of the repository", o.Name, o.SubType)
+		}
+	}
+}
+
+// TestFilesReadFromHead scans a working directory without the files it has
+// at HEAD, as the ones of remote clones: the .mailmap and ignore-revs-file
+// are read from HEAD.
+func TestFilesReadFromHead(t *testing.T) {
+	dir := testRepo(t, "password=hunter2\n", "clean\n")
+
+	out, err := gitCommand(dir, "rev-parse", "HEAD~1").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		".mailmap":               "Proper Name <proper@example.com> <test@example.com>\n",
+		".git-blame-ignore-revs": string(out),
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testGit(t, dir, "add", ".")
+	testGit(t, dir, "commit", "--quiet", "-m", "add .mailmap and ignored revisions")
+	for name := range files {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	objects, err := SourceTypeGit.LoadObjects(dir, seekret.LoadOptions{
+		"commit-files":     true,
+		"mailmap":          true,
+		"ignore-revs-file": ".git-blame-ignore-revs",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, o := range objects {
+		if strings.Contains(string(o.Content), "hunter2") {
+			t.Errorf("%s: content of an ignored commit loaded", o.Name)
+		}
+		if author, _ := o.GetMetadata("author"); author != "Proper Name <proper@example.com>" {
+			t.Errorf("%s: author %q, want the one of the .mailmap", o.Name, author)
+		}
+	}
+}
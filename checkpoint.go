@@ -0,0 +1,106 @@
+package sourcegit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// checkpoint persists which commits a long history walk has already
+// processed, so that an interrupted load can be resumed with "resume".
+//
+// Objects of processed commits are not part of the checkpoint: resuming only
+// makes sense when objects are consumed as they are produced, e.g. from an
+// ObjectFilter.
+type checkpoint struct {
+	path     string
+	interval int
+	pending  int
+	state    checkpointState
+}
+
+type checkpointState struct {
+	Source    string    `json:"source"`
+	Commits   []string  `json:"commits"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newCheckpoint(path string, interval int, source string) *checkpoint {
+	if interval <= 0 {
+		interval = 1000
+	}
+
+	return &checkpoint{
+		path:     path,
+		interval: interval,
+		state: checkpointState{
+			Source: source,
+		},
+	}
+}
+
+// load reads the checkpoint left by a previous load of the same source. A
+// missing checkpoint file is not an error: there is just nothing to resume.
+func (c *checkpoint) load() error {
+	data, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var state checkpointState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("invalid checkpoint %s: %v", c.path, err)
+	}
+
+	if state.Source != c.state.Source {
+		return fmt.Errorf("checkpoint %s belongs to source %s", c.path, state.Source)
+	}
+	c.state = state
+
+	return nil
+}
+
+// done records commit as processed, writing the checkpoint every interval
+// commits.
+func (c *checkpoint) done(commit string) error {
+	c.state.Commits = append(c.state.Commits, commit)
+
+	c.pending++
+	if c.pending < c.interval {
+		return nil
+	}
+
+	return c.flush()
+}
+
+func (c *checkpoint) flush() error {
+	c.pending = 0
+	c.state.UpdatedAt = time.Now().UTC()
+
+	data, err := json.Marshal(c.state)
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.path)
+}
+
+// finish removes the checkpoint of a load that completed.
+func (c *checkpoint) finish() error {
+	err := os.Remove(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,92 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// isTemporarySource reports whether opening source creates a temporary
+// repository (clones of remotes, bundles and packs) to be removed once done.
+func isTemporarySource(source string) bool {
+	if _, remote := normalizeGitUri(source); remote {
+		return true
+	}
+	if _, ok := gistCloneUrl(source); ok {
+		return true
+	}
+
+	return isBundleSource(source) || isPackSource(source)
+}
+
+// trackRepo records an open repository of the source, along with the
+// temporary directory holding it, if any.
+func (s *SourceGit) trackRepo(repo *git.Repository, temporary bool) {
+	dir := ""
+	if temporary {
+		dir = repo.Workdir()
+		if repo.IsBare() || dir == "" {
+			dir = repo.Path()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.repos == nil {
+		s.repos = make(map[*git.Repository]string)
+	}
+	s.repos[repo] = dir
+}
+
+// releaseRepo frees a repository opened by the source and removes its
+// temporary directory. Repositories already released (by Close) are left
+// alone.
+func (s *SourceGit) releaseRepo(repo *git.Repository) {
+	s.mu.Lock()
+	dir, ok := s.repos[repo]
+	delete(s.repos, repo)
+	s.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	path := repo.Path()
+	repo.Free()
+	releasePartialView(path)
+	if dir != "" {
+		removeTempDir(dir)
+	}
+}
+
+// holdsRepo reports whether repo is still open.
+func (s *SourceGit) holdsRepo(repo *git.Repository) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.repos[repo]
+	return ok
+}
+
+// Close releases every repository the source still holds open, such as
+// those of LazyObjects that were not closed, and removes their temporary
+// clones. Loads running concurrently must be over.
+func (s *SourceGit) Close() error {
+	s.mu.Lock()
+	repos := s.repos
+	s.repos = nil
+	s.mu.Unlock()
+
+	var firstErr error
+	for repo, dir := range repos {
+		path := repo.Path()
+		repo.Free()
+		releasePartialView(path)
+		if dir != "" {
+			if err := removeTempDir(dir); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,57 @@
+package sourcegit
+
+import (
+	"fmt"
+)
+
+// cloneFilterOptions returns the options for the git CLI to have the server
+// leave out what clone-blob-limit and clone-tree-filter exclude, or nothing
+// when no filter is requested. The filter is asked over protocol v2;
+// servers not supporting it ignore it and send the complete repository.
+// Filtered clones are partial clones, opened with openPartialClone.
+func cloneFilterOptions(opt SourceGitLoadOptions) []string {
+	var filters []string
+
+	if opt.CloneBlobLimit > 0 {
+		filters = append(filters, fmt.Sprintf("blob:limit=%d", opt.CloneBlobLimit))
+	}
+
+	// Trees at depth n hold the files with n+1 path components.
+	if opt.CloneTreeFilter && opt.MaxPathDepth > 0 {
+		filters = append(filters, fmt.Sprintf("tree:%d", opt.MaxPathDepth+1))
+	}
+
+	switch len(filters) {
+	case 0:
+		return nil
+	case 1:
+		return []string{"-c", "protocol.version=2", "--filter=" + filters[0]}
+	default:
+		return []string{"-c", "protocol.version=2", "--filter=combine:" + filters[0] + "+" + filters[1]}
+	}
+}
+
+// cloneFilterConflicts returns the options that need what the clone filters
+// leave out: diffs read the content of both sides of every change, and walk
+// whole trees, without going through partial-clone.
+func (opt SourceGitLoadOptions) cloneFilterConflicts() []string {
+	trees := opt.CloneTreeFilter && opt.MaxPathDepth > 0
+
+	var conflicts []string
+	for _, c := range []struct {
+		name string
+		set  bool
+	}{
+		{"commit-diffs", opt.CommitDiffs},
+		{"dedup-patch-id", opt.DedupPatchId},
+		{"deleted-files", opt.DeletedFiles && trees},
+		{"at-head", opt.AtHead && trees},
+		{"pathspec", len(opt.Pathspec) > 0 && trees},
+	} {
+		if c.set {
+			conflicts = append(conflicts, c.name)
+		}
+	}
+
+	return conflicts
+}
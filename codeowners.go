@@ -0,0 +1,125 @@
+package sourcegit
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// codeownersPaths are the locations where GitHub and GitLab look for the
+// CODEOWNERS file, in order.
+var codeownersPaths = []string{"CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// codeowners maps paths to their owners following the rules of a CODEOWNERS
+// file. A nil codeowners owns nothing.
+type codeowners struct {
+	rules []codeownersRule
+}
+
+type codeownersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// loadCodeowners reads the CODEOWNERS file at HEAD. It returns nil when the
+// repository has none.
+func loadCodeowners(repo *git.Repository) (*codeowners, error) {
+	for _, p := range codeownersPaths {
+		data, err := headFile(repo, p)
+		if err != nil {
+			return nil, err
+		}
+		if data != nil {
+			return parseCodeowners(data), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func parseCodeowners(data []byte) *codeowners {
+	c := &codeowners{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// GitLab section headers ("[Section]") are not rules.
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		re, err := regexp.Compile(codeownersPattern(fields[0]))
+		if err != nil {
+			continue
+		}
+		c.rules = append(c.rules, codeownersRule{pattern: re, owners: fields[1:]})
+	}
+
+	return c
+}
+
+// codeownersPattern translates a gitignore-style CODEOWNERS pattern into a
+// regular expression over slash separated paths.
+func codeownersPattern(pattern string) string {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var re strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			re.WriteString("[^/]*")
+		case pattern[i] == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	prefix := "^"
+	if !anchored {
+		prefix = "^(?:.*/)?"
+	}
+	if dirOnly {
+		return prefix + re.String() + "/.*$"
+	}
+
+	// A last segment with wildcards matches the entries of a directory, as
+	// "docs/*" does, and nothing deeper; a literal one may name a directory
+	// and match everything inside.
+	last := pattern[strings.LastIndex(pattern, "/")+1:]
+	if last != "**" && strings.ContainsAny(last, "*?") {
+		return prefix + re.String() + "$"
+	}
+
+	return prefix + re.String() + "(?:/.*)?$"
+}
+
+// owners returns the owners of p: those of the last matching rule.
+func (c *codeowners) owners(p string) []string {
+	if c == nil {
+		return nil
+	}
+
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if c.rules[i].pattern.MatchString(p) {
+			return c.rules[i].owners
+		}
+	}
+
+	return nil
+}
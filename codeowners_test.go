@@ -0,0 +1,42 @@
+package sourcegit
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestCodeownersPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*", "a/b/c.txt", true},
+		{"*.js", "app.js", true},
+		{"*.js", "src/app.js", true},
+		{"*.js", "app.jsx", false},
+		{"docs/*", "docs/a.txt", true},
+		{"docs/*", "docs/a/b.txt", false},
+		{"docs/*", "src/docs/a.txt", false},
+		{"docs/**", "docs/a/b.txt", true},
+		{"docs", "docs/a/b.txt", true},
+		{"docs", "src/docs/a.txt", true},
+		{"docs", "docsite/a.txt", false},
+		{"/docs/", "docs/a/b.txt", true},
+		{"/docs/", "src/docs/a.txt", false},
+		{"apps/", "src/apps/main.go", true},
+		{"apps/", "apps", false},
+		{"**/logs", "a/b/logs/x.log", true},
+		{"/build/logs/", "build/logs/x.log", true},
+		{"src/v?", "src/v1", true},
+		{"src/v?", "src/v1/main.go", false},
+		{"a.b", "axb", false},
+	}
+
+	for _, test := range tests {
+		re := regexp.MustCompile(codeownersPattern(test.pattern))
+		if got := re.MatchString(test.path); got != test.want {
+			t.Errorf("%q matching %q: %v, want %v", test.pattern, test.path, got, test.want)
+		}
+	}
+}
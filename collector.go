@@ -0,0 +1,275 @@
+package sourcegit
+
+import (
+	"fmt"
+	"github.com/apuigsech/seekret/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// objectCollector accumulates the objects of a single LoadObjects call. Every
+// object goes through add, which is where the object filter is applied.
+type objectCollector struct {
+	filter   ObjectFilter
+	redactor Redactor
+	metrics  Metrics
+	objects  []models.Object
+
+	report *LoadReport
+	opt    SourceGitLoadOptions
+
+	// Stable identifier of the repository, set on every object.
+	fingerprint string
+
+	// Name of the promisor remote when loading from a partial clone, the
+	// git directory of the partial clone, where the blobs it has not
+	// delivered yet are fetched, and those blobs. Objects referring to them
+	// are held back until resolvePromised.
+	partialCloneRemote string
+	partialCloneDir    string
+	promised           map[string]bool
+	held               []models.Object
+
+	// Identities canonicalization, nil with mailmap disabled.
+	mailmap *mailmap
+	// Owners of file contents, nil without codeowners.
+	codeowners *codeowners
+
+	incremental incrementalContent
+	timezones   timezoneStats
+	lifetimes   blobLifetimes
+
+	// Content bytes held in memory, and where content goes once
+	// memory-limit is exceeded with memory-limit-action "spool".
+	bytes int64
+	spool *contentSpool
+
+	checkpoint *checkpoint
+	// When max-duration is reached, zero without it.
+	deadline time.Time
+	// Ref tips of the previous load, nil without since-last-scan.
+	scanState *scanState
+
+	// Blobs never loaded, and blobs always returned (skip-blobs,
+	// include-blobs).
+	skipBlobs    map[string]bool
+	includeBlobs map[string]bool
+
+	// Blobs returned by previous loads, and the ones this load returned.
+	dedup     DedupCache
+	dedupLoad map[string]bool
+	// Commit messages to drop, nil when all are kept.
+	messageNoise *messageNoise
+	// Fingerprints searched for, nil without known-secrets.
+	knownSecrets knownSecrets
+	// What was scanned, nil without evidence-file.
+	evidence *evidence
+	// Where objects go instead of being returned, nil to return them, and
+	// the stream-file behind it.
+	emit   func(o *models.Object) error
+	stream *objectStream
+}
+
+func (c *objectCollector) add(objectList ...models.Object) error {
+	for i := range objectList {
+		included := c.includedObject(&objectList[i])
+		if objectList[i].SubType == "file-content" && c.excludedPath(objectList[i].Name) && !included {
+			continue
+		}
+		if objectList[i].SubType == "file-content" && c.tooDeep(objectList[i].Name, false) {
+			continue
+		}
+		if id, err := objectList[i].GetMetadata("uniq-id"); err == nil && c.promised[id] {
+			c.held = append(c.held, objectList[i])
+			continue
+		}
+		c.metricAdd(MetricBytesRead, int64(len(objectList[i].Content)))
+		if c.knownSecrets != nil && !c.knownSecret(&objectList[i]) {
+			continue
+		}
+		if objectList[i].SubType == "file-content" && c.generated(objectList[i].Name, objectList[i].Content) {
+			if c.opt.SkipGenerated && !included {
+				continue
+			}
+			objectList[i].SetMetadata("generated", "true", models.MetadataAttributes{})
+		}
+		if c.fingerprint != "" {
+			objectList[i].SetMetadata("repo-fingerprint", c.fingerprint, models.MetadataAttributes{})
+		}
+		if objectList[i].SubType == "file-content" {
+			if t := fileType(objectList[i].Name, objectList[i].Content); t != "" {
+				objectList[i].SetMetadata("filetype", t, models.MetadataAttributes{})
+			}
+			if isBuildConfig(objectList[i].Name) {
+				objectList[i].SetMetadata("build-config", "true", models.MetadataAttributes{})
+			}
+			if owners := c.codeowners.owners(objectList[i].Name); len(owners) > 0 {
+				objectList[i].SetMetadata("owners", strings.Join(owners, " "), models.MetadataAttributes{})
+			}
+		}
+		if c.opt.MetadataOnly && objectList[i].SubType == "file-content" && objectList[i].Content != nil {
+			objectList[i].SetMetadata("size", strconv.Itoa(len(objectList[i].Content)), models.MetadataAttributes{})
+			objectList[i].Content = nil
+		}
+		if c.opt.HashOnly {
+			c.hashOnly(&objectList[i])
+		} else {
+			c.redact(&objectList[i])
+		}
+		if c.filter != nil && !c.filter(&objectList[i]) {
+			continue
+		}
+		if !included {
+			if dup, err := c.duplicate(&objectList[i]); err != nil {
+				return err
+			} else if dup {
+				continue
+			}
+		}
+
+		if c.emit != nil {
+			c.renameType(&objectList[i])
+			if err := c.evidence.object(&objectList[i]); err != nil {
+				return err
+			}
+			if err := c.emit(&objectList[i]); err != nil {
+				return err
+			}
+			c.metricAdd(MetricObjectsEmitted, 1)
+			continue
+		}
+
+		if err := c.account(&objectList[i]); err != nil {
+			return err
+		}
+		c.objects = append(c.objects, objectList[i])
+		c.metricAdd(MetricObjectsEmitted, 1)
+	}
+
+	return nil
+}
+
+// account enforces memory-limit on the content accumulated so far. Past the
+// limit, the load is aborted or every content is spooled to disk from then
+// on, depending on memory-limit-action.
+func (c *objectCollector) account(o *models.Object) error {
+	if c.spool != nil {
+		return c.spool.store(o)
+	}
+
+	c.bytes += int64(len(o.Content))
+	if c.opt.MemoryLimit <= 0 || c.bytes <= c.opt.MemoryLimit {
+		return nil
+	}
+
+	if c.opt.MemoryLimitAction != "spool" {
+		return fmt.Errorf("loaded object content exceeds memory-limit of %d bytes after %d objects; set memory-limit-action to \"spool\" or narrow the scan", c.opt.MemoryLimit, len(c.objects))
+	}
+
+	spool, err := newContentSpool(c.opt.SpoolDir)
+	if err != nil {
+		return err
+	}
+	c.spool = spool
+	c.report.SpoolDir = spool.dir
+
+	for i := range c.objects {
+		if err := spool.store(&c.objects[i]); err != nil {
+			return err
+		}
+	}
+	c.bytes = 0
+
+	return spool.store(o)
+}
+
+// cleanup releases what a failed load leaves behind, and saves its
+// checkpoint so it can be resumed.
+func (c *objectCollector) cleanup() {
+	if c.spool != nil {
+		c.spool.remove()
+		c.report.SpoolDir = ""
+	}
+
+	if err := c.stream.close(); err != nil {
+		c.report.Warnings = append(c.report.Warnings, "closing stream-file: "+err.Error())
+	}
+
+	if c.checkpoint != nil {
+		if err := c.checkpoint.flush(); err != nil {
+			c.report.Warnings = append(c.report.Warnings, "saving checkpoint: "+err.Error())
+		}
+	}
+}
+
+// finish completes a successful load.
+func (c *objectCollector) finish() error {
+	c.renameTypes()
+
+	if err := c.stream.close(); err != nil {
+		return err
+	}
+
+	if err := c.evidence.write(c.objects); err != nil {
+		return err
+	}
+
+	// A load cut short by max-duration is resumed from its checkpoint, and
+	// the commits it left unscanned are not behind the scan state tips.
+	if c.report.Stopped != nil {
+		if c.checkpoint != nil {
+			return c.checkpoint.flush()
+		}
+		return nil
+	}
+
+	if err := c.scanState.save(); err != nil {
+		return err
+	}
+
+	if c.checkpoint != nil {
+		return c.checkpoint.finish()
+	}
+
+	return nil
+}
+
+// renameTypes applies type-names and type-prefix to the objects returned.
+func (c *objectCollector) renameTypes() {
+	if len(c.opt.TypeNames) == 0 && c.opt.TypePrefix == "" {
+		return
+	}
+
+	for i := range c.objects {
+		c.renameType(&c.objects[i])
+	}
+}
+
+func (c *objectCollector) renameType(o *models.Object) {
+	if len(c.opt.TypeNames) == 0 && c.opt.TypePrefix == "" {
+		return
+	}
+
+	if name, ok := c.opt.TypeNames[o.SubType]; ok {
+		o.SubType = name
+	}
+	o.SubType = c.opt.TypePrefix + o.SubType
+}
+
+// corrupt records an object that could not be read. It returns a non-nil
+// error only when the load has to be aborted (fail-on-corruption).
+func (c *objectCollector) corrupt(id string, path string, commit string, err error) error {
+	if c.opt.FailOnCorruption {
+		return fmt.Errorf("unable to read object %s: %v", id, err)
+	}
+
+	c.report.Corrupt = append(c.report.Corrupt, CorruptObject{
+		Id:     id,
+		Path:   path,
+		Commit: commit,
+		Err:    err,
+	})
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package sourcegit
+
+import (
+	"bytes"
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+	"path"
+	"strings"
+)
+
+// objectsFromCommitDiff emits, for every file changed by commit relative to
+// its first parent, the lines the commit adds to it. Binary files are
+// skipped, and so are files whose type is not in diff-filetypes when set.
+func objectsFromCommitDiff(repo *git.Repository, commit *git.Commit, opt SourceGitLoadOptions, collector *objectCollector) ([]models.Object, error) {
+	diffOpts, err := git.DefaultDiffOptions()
+	if err != nil {
+		return nil, err
+	}
+	diffOpts.Pathspec = opt.Pathspec
+
+	diff, err := diffFirstParent(repo, commit, &diffOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer diff.Free()
+
+	objectList, err := diffAddedLines(diff, "commit-diff", func(p string) bool {
+		return !collector.excludedPath(p) && matchDiffFiletypes(p, opt.DiffFiletypes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range objectList {
+		objectList[i].SetMetadata("commit", commit.Id().String(), models.MetadataAttributes{})
+	}
+
+	return objectList, nil
+}
+
+// diffAddedLines returns, for every file of diff that keep accepts, an object of
+// the given subtype holding the lines the diff adds to it. Deleted and binary
+// files are skipped, and so are files the diff adds no line to.
+func diffAddedLines(diff *git.Diff, subtype string, keep func(p string) bool) ([]models.Object, error) {
+	var objectList []models.Object
+
+	type fileDiff struct {
+		o     *models.Object
+		added bytes.Buffer
+	}
+	var files []*fileDiff
+
+	err := diff.ForEach(func(delta git.DiffDelta, progress float64) (git.DiffForEachHunkCallback, error) {
+		p := delta.NewFile.Path
+		if delta.Status == git.DeltaDeleted || delta.Flags&git.DiffFlagBinary != 0 || !keep(p) {
+			return nil, nil
+		}
+
+		f := &fileDiff{o: models.NewObject(p, Type, subtype, nil)}
+		if !delta.NewFile.Oid.IsZero() {
+			f.o.SetMetadata("blob", delta.NewFile.Oid.String(), models.MetadataAttributes{})
+		}
+		if t := fileType(p, nil); t != "" {
+			f.o.SetMetadata("filetype", t, models.MetadataAttributes{})
+		}
+		files = append(files, f)
+
+		return func(hunk git.DiffHunk) (git.DiffForEachLineCallback, error) {
+			return func(line git.DiffLine) error {
+				if line.Origin == git.DiffLineAddition {
+					f.added.WriteString(line.Content)
+				}
+				return nil
+			}, nil
+		}, nil
+	}, git.DiffDetailLines)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		if f.added.Len() == 0 {
+			continue
+		}
+		f.o.Content = f.added.Bytes()
+		objectList = append(objectList, *f.o)
+	}
+
+	return objectList, nil
+}
+
+// matchDiffFiletypes reports whether p is of one of the types, given either
+// as filetype names ("yaml", "dotenv") or extensions ("yml", "env"). An
+// empty list matches every file.
+func matchDiffFiletypes(p string, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+
+	t := fileType(p, nil)
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(p)), ".")
+	base := strings.ToLower(path.Base(p))
+	for _, want := range types {
+		want = strings.ToLower(strings.TrimPrefix(want, "."))
+		if want == t || want == ext || base == "."+want || strings.HasPrefix(base, "."+want+".") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,64 @@
+package sourcegit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+)
+
+// objectsFromCommitMessage emits the message of commit, either whole or, with
+// split-commit-messages, as separate subject and body objects told apart by
+// the "message-part" metadata.
+func objectsFromCommitMessage(commit *git.Commit, opt SourceGitLoadOptions) []models.Object {
+	var objectList []models.Object
+
+	if !opt.SplitCommitMessages {
+		o := models.NewObject(fmt.Sprintf("commit-%s", commit.Id()), Type, "commit-message", []byte(commit.Message()))
+		o.SetMetadata("commit", commit.Id().String(), models.MetadataAttributes{})
+		return append(objectList, *o)
+	}
+
+	subject, body := splitCommitMessage(commit.Message())
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"subject", subject},
+		{"body", body},
+	}
+
+	for _, part := range parts {
+		if part.content == "" {
+			continue
+		}
+
+		o := models.NewObject(fmt.Sprintf("commit-%s-%s", commit.Id(), part.name), Type, "commit-message", []byte(part.content))
+		o.SetMetadata("commit", commit.Id().String(), models.MetadataAttributes{})
+		o.SetMetadata("message-part", part.name, models.MetadataAttributes{})
+		objectList = append(objectList, *o)
+	}
+
+	return objectList
+}
+
+// splitCommitMessage splits a commit message the way git does: the subject
+// is the first paragraph, joined into a single line, and the body is
+// everything after the blank line that follows it.
+func splitCommitMessage(message string) (string, string) {
+	message = strings.Replace(message, "\r\n", "\n", -1)
+	message = strings.TrimLeft(message, "\n")
+
+	var subject []string
+	lines := strings.Split(message, "\n")
+	i := 0
+	for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+		subject = append(subject, strings.TrimSpace(lines[i]))
+	}
+
+	body := strings.Trim(strings.Join(lines[i:], "\n"), "\n")
+
+	return strings.Join(subject, " "), body
+}
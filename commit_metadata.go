@@ -0,0 +1,83 @@
+package sourcegit
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+)
+
+type commitSignature struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	When  time.Time `json:"when"`
+}
+
+// commitMetadata is the content of "commit-metadata" objects.
+type commitMetadata struct {
+	Id           string          `json:"id"`
+	Author       commitSignature `json:"author"`
+	Committer    commitSignature `json:"committer"`
+	Message      string          `json:"message"`
+	Parents      []string        `json:"parents"`
+	ChangedPaths []string        `json:"changed_paths"`
+}
+
+// objectFromCommitMetadata emits the structured description of commit as a
+// JSON document, for rules that operate on commit data rather than text.
+// Identities are canonicalized with the mailmap.
+func objectFromCommitMetadata(repo *git.Repository, commit *git.Commit, mm *mailmap) (*models.Object, error) {
+	paths, err := changedPaths(repo, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := commitMetadata{
+		Id:           commit.Id().String(),
+		Author:       mm.signature(commit.Author()),
+		Committer:    mm.signature(commit.Committer()),
+		Message:      commit.Message(),
+		Parents:      []string{},
+		ChangedPaths: paths,
+	}
+	for i := uint(0); i < commit.ParentCount(); i++ {
+		meta.Parents = append(meta.Parents, commit.ParentId(i).String())
+	}
+
+	content, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	o := models.NewObject(fmt.Sprintf("commit-metadata-%s", commit.Id()), Type, "commit-metadata", content)
+	o.SetMetadata("commit", commit.Id().String(), models.MetadataAttributes{})
+
+	return o, nil
+}
+
+func newCommitSignature(sig *git.Signature) commitSignature {
+	if sig == nil {
+		return commitSignature{}
+	}
+
+	return commitSignature{
+		Name:  sig.Name,
+		Email: sig.Email,
+		When:  sig.When,
+	}
+}
+
+// setIdentityMetadata records the canonical author and committer of the
+// commit the objects come from, as "Name <email>", and the author timezone.
+func setIdentityMetadata(objectList []models.Object, commit *git.Commit, mm *mailmap) {
+	author := mm.signature(commit.Author())
+	committer := mm.signature(commit.Committer())
+
+	for i := range objectList {
+		objectList[i].SetMetadata("author", fmt.Sprintf("%s <%s>", author.Name, author.Email), models.MetadataAttributes{})
+		objectList[i].SetMetadata("committer", fmt.Sprintf("%s <%s>", committer.Name, committer.Email), models.MetadataAttributes{})
+		objectList[i].SetMetadata("author-tz", formatTimezone(authorTimezone(commit)), models.MetadataAttributes{})
+	}
+}
@@ -0,0 +1,67 @@
+package sourcegit
+
+import (
+	"time"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// commitTime returns the time of commit the date filters, sampling periods
+// and time ordering go by: its committer time, or its author time with
+// date-field "author". Rebases and cherry-picks keep the author time and
+// renew the committer time, so the two can be months apart.
+func commitTime(commit *git.Commit, field string) time.Time {
+	if field == "author" {
+		return commit.Author().When
+	}
+
+	return commit.Committer().When
+}
+
+// inDateRange reports whether commit falls within since and until.
+func inDateRange(commit *git.Commit, opt SourceGitLoadOptions) bool {
+	if opt.Since.IsZero() && opt.Until.IsZero() {
+		return true
+	}
+
+	when := commitTime(commit, opt.DateField)
+	if !opt.Since.IsZero() && when.Before(opt.Since) {
+		return false
+	}
+	if !opt.Until.IsZero() && when.After(opt.Until) {
+		return false
+	}
+
+	return true
+}
+
+// dateOption accepts dates as time.Time, or as RFC 3339 or "2006-01-02"
+// strings.
+func dateOption(v interface{}) (time.Time, bool) {
+	switch d := v.(type) {
+	case time.Time:
+		return d, true
+	case string:
+		if t, err := time.Parse(time.RFC3339, d); err == nil {
+			return t, true
+		}
+		if t, err := time.Parse("2006-01-02", d); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// untilOption is dateOption for the end of a range: a "2006-01-02" string
+// stands for the last instant of that day rather than its midnight, so that
+// the day is included.
+func untilOption(v interface{}) (time.Time, bool) {
+	if d, ok := v.(string); ok {
+		if t, err := time.Parse("2006-01-02", d); err == nil {
+			return t.Add(24*time.Hour - time.Nanosecond), true
+		}
+	}
+
+	return dateOption(v)
+}
@@ -0,0 +1,128 @@
+package sourcegit
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ConcurrencyLimits bounds the clones running at once across the loads of a
+// source, so that fleet scans stay within the abuse limits of hosting
+// providers.
+type ConcurrencyLimits struct {
+	// Clones running at once, 0 for no limit.
+	Total int
+	// Clones running at once from a single host, 0 for no limit.
+	PerHost int
+	// Limits of given hosts (e.g. "github.com": 3), overriding PerHost.
+	Hosts map[string]int
+}
+
+// SetConcurrencyLimits installs the clone limits of the source, replacing
+// any previous ones. Clones already running are not affected. The zero
+// value removes every limit.
+func (s *SourceGit) SetConcurrencyLimits(limits ConcurrencyLimits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limiter = newCloneLimiter(limits)
+}
+
+// acquireClone waits until a clone of source fits in the concurrency limits
+// and returns the function to call once the clone is over. Local sources
+// are never limited.
+func (s *SourceGit) acquireClone(source string) func() {
+	s.mu.RLock()
+	limiter := s.limiter
+	s.mu.RUnlock()
+
+	gitUri, remote := normalizeGitUri(source)
+	if cloneUrl, ok := gistCloneUrl(source); ok {
+		gitUri, remote = cloneUrl, true
+	}
+	if limiter == nil || !remote {
+		return func() {}
+	}
+
+	return limiter.acquire(gitUriHost(gitUri))
+}
+
+type cloneLimiter struct {
+	limits ConcurrencyLimits
+	total  chan struct{}
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+func newCloneLimiter(limits ConcurrencyLimits) *cloneLimiter {
+	l := &cloneLimiter{
+		limits: limits,
+		hosts:  make(map[string]chan struct{}),
+	}
+	if limits.Total > 0 {
+		l.total = make(chan struct{}, limits.Total)
+	}
+
+	return l
+}
+
+func (l *cloneLimiter) hostSlots(host string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if slots, ok := l.hosts[host]; ok {
+		return slots
+	}
+
+	limit := l.limits.PerHost
+	if n, ok := l.limits.Hosts[host]; ok {
+		limit = n
+	}
+
+	var slots chan struct{}
+	if limit > 0 {
+		slots = make(chan struct{}, limit)
+	}
+	l.hosts[host] = slots
+
+	return slots
+}
+
+// acquire takes a slot of host, then a slot of the total, so that a clone
+// waiting for its host does not hold back clones from other hosts.
+func (l *cloneLimiter) acquire(host string) func() {
+	hostSlots := l.hostSlots(host)
+
+	if hostSlots != nil {
+		hostSlots <- struct{}{}
+	}
+	if l.total != nil {
+		l.total <- struct{}{}
+	}
+
+	return func() {
+		if l.total != nil {
+			<-l.total
+		}
+		if hostSlots != nil {
+			<-hostSlots
+		}
+	}
+}
+
+// gitUriHost returns the lower-cased host of a remote, given as URL or scp
+// style.
+func gitUriHost(gitUri string) string {
+	if strings.Contains(gitUri, "://") {
+		if u, err := url.Parse(gitUri); err == nil {
+			return strings.ToLower(u.Hostname())
+		}
+	}
+
+	if m := sshScpRegexp.FindStringSubmatch(gitUri); m != nil {
+		return strings.ToLower(m[2])
+	}
+
+	return ""
+}
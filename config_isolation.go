@@ -0,0 +1,56 @@
+package sourcegit
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+var (
+	isolateOnce    sync.Once
+	isolateErr     error
+	configIsolated int32
+)
+
+// IsolateProcessConfig stops the system, global and XDG git configuration of
+// the host from applying to anything the process does from then on, in every
+// SourceGit: repositories opened, url.<base>.insteadOf rewrites, credential
+// helpers and git CLI commands. Only the configuration of the repositories
+// themselves is read, so scans are reproducible on shared hosts (no
+// core.fsmonitor, credential helpers or proxies leaking in). libgit2 keeps
+// its configuration search paths for the whole process, so the isolation
+// cannot be limited to a load, nor undone: call it once at startup, before
+// any load.
+func IsolateProcessConfig() error {
+	isolateOnce.Do(func() {
+		for _, level := range []git.ConfigLevel{git.ConfigLevelSystem, git.ConfigLevelXDG, git.ConfigLevelGlobal} {
+			if err := git.SetSearchPath(level, os.DevNull); err != nil {
+				isolateErr = err
+				return
+			}
+		}
+		atomic.StoreInt32(&configIsolated, 1)
+	})
+
+	return isolateErr
+}
+
+func isConfigIsolated() bool {
+	return atomic.LoadInt32(&configIsolated) != 0
+}
+
+// isolatedConfigEnv returns the environment keeping the git CLI from reading
+// the host configuration once isolated. GIT_CONFIG_GLOBAL needs git 2.32.
+func isolatedConfigEnv() []string {
+	if !isConfigIsolated() {
+		return nil
+	}
+
+	return []string{
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL=" + os.DevNull,
+		"XDG_CONFIG_HOME=" + os.DevNull,
+	}
+}
@@ -0,0 +1,126 @@
+package sourcegit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// newCredentialsCallback returns a credentials callback for a single remote
+// operation. libgit2 keeps calling the callback for as long as the server
+// rejects the offered credentials, so every source is offered only once.
+// sshAlias is the ssh_config Host the remote was given as, if any.
+func newCredentialsCallback(sshAlias string) git.CredentialsCallback {
+	var triedHelper, triedNetrc, triedSsh bool
+
+	return git.NewCredentialsCallback(func(gitUri string, username string, allowedTypes git.CredentialType) (*git.Credential, error) {
+		if allowedTypes&git.CredentialTypeUserpassPlaintext != 0 && !triedHelper {
+			triedHelper = true
+			if user, pass, err := credentialHelperFill(gitUri, username); err == nil {
+				return userpassCredential(user, pass)
+			}
+		}
+
+		if allowedTypes&git.CredentialTypeUserpassPlaintext != 0 && !triedNetrc {
+			triedNetrc = true
+			if u, err := url.Parse(gitUri); err == nil {
+				if user, pass, err := netrcCredentials(u.Host); err == nil {
+					return userpassCredential(user, pass)
+				}
+			}
+		}
+
+		if allowedTypes&git.CredentialTypeSSHKey != 0 && !triedSsh {
+			triedSsh = true
+			return sshKeyCredentials(gitUri, sshAlias)
+		}
+
+		return noCredential()
+	})
+}
+
+// credentialHelperFill asks the credential helpers configured for the git CLI
+// (git credential fill) for a username and password for the given remote.
+func credentialHelperFill(gitUri string, username string) (string, string, error) {
+	if isConfigIsolated() {
+		return "", "", fmt.Errorf("credential helpers disabled by IsolateProcessConfig")
+	}
+
+	u, err := url.Parse(gitUri)
+	if err != nil {
+		return "", "", err
+	}
+
+	var input bytes.Buffer
+	fmt.Fprintf(&input, "protocol=%s\n", u.Scheme)
+	fmt.Fprintf(&input, "host=%s\n", u.Host)
+	fmt.Fprintf(&input, "path=%s\n", strings.TrimPrefix(u.Path, "/"))
+	if username != "" {
+		fmt.Fprintf(&input, "username=%s\n", username)
+	}
+	input.WriteString("\n")
+
+	cmd := gitCommand("", "credential", "fill")
+	cmd.Stdin = &input
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", err
+	}
+
+	var user, pass string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "username":
+			user = kv[1]
+		case "password":
+			pass = kv[1]
+		}
+	}
+
+	if user == "" || pass == "" {
+		return "", "", fmt.Errorf("no credentials returned by git credential helper for %s", u.Host)
+	}
+
+	return user, pass, nil
+}
+
+func sshKeyCredentials(gitUri string, alias string) (*git.Credential, error) {
+	u, err := url.Parse(gitUri)
+	if err != nil {
+		return noCredential()
+	}
+
+	user := "git"
+	if u.User != nil && u.User.Username() != "" {
+		user = u.User.Username()
+	}
+
+	// The Host section may be the one of an alias the source was given as.
+	c := loadSshConfig()
+	var idFile string
+	if alias != "" {
+		idFile = sshHostParam(c, alias, "IdentityFile")
+	}
+	if idFile == "" {
+		idFile = sshHostParam(c, u.Hostname(), "IdentityFile")
+	}
+	if idFile == "" {
+		return noCredential()
+	}
+	if strings.HasPrefix(idFile, "~/") {
+		idFile = os.Getenv("HOME") + idFile[1:]
+	}
+	idFilePub := idFile + ".pub"
+
+	return sshKeyCredential(user, idFilePub, idFile, "")
+}
@@ -0,0 +1,87 @@
+package sourcegit
+
+import (
+	"sync"
+
+	"github.com/apuigsech/seekret/models"
+)
+
+// DedupCache remembers the blobs returned by the loads of a source, so that
+// a fleet scan returns every blob once, however many repositories vendor it.
+// Blobs are keyed by their "uniq-id". Implementations can keep the keys in
+// memory (MemoryDedupCache) or in an embedded database such as bolt or
+// badger to share them across processes. They must be safe for concurrent
+// use when several loads run in parallel.
+type DedupCache interface {
+	// Seen records id and tells whether it had been recorded before.
+	Seen(id string) (bool, error)
+}
+
+// SetDedupCache installs the dedup cache of the source, replacing any
+// previous one. A nil cache disables cross-load deduplication.
+func (s *SourceGit) SetDedupCache(cache DedupCache) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dedup = cache
+}
+
+func (s *SourceGit) dedupCache() DedupCache {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.dedup
+}
+
+// MemoryDedupCache is a DedupCache living as long as the process.
+type MemoryDedupCache struct {
+	mu  sync.Mutex
+	ids map[string]bool
+}
+
+func NewMemoryDedupCache() *MemoryDedupCache {
+	return &MemoryDedupCache{
+		ids: make(map[string]bool),
+	}
+}
+
+func (c *MemoryDedupCache) Seen(id string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ids[id] {
+		return true, nil
+	}
+	c.ids[id] = true
+
+	return false, nil
+}
+
+// duplicate tells whether o is a blob already returned by a previous load.
+// Within a load, a blob returned once is returned every time it is reached.
+func (c *objectCollector) duplicate(o *models.Object) (bool, error) {
+	if c.dedup == nil {
+		return false, nil
+	}
+
+	id, err := o.GetMetadata("uniq-id")
+	if err != nil || id == "" || c.dedupLoad[id] {
+		return false, nil
+	}
+
+	seen, err := c.dedup.Seen(id)
+	if err != nil {
+		return false, err
+	}
+	if seen {
+		c.report.DuplicateBlobs++
+		return true, nil
+	}
+
+	if c.dedupLoad == nil {
+		c.dedupLoad = make(map[string]bool)
+	}
+	c.dedupLoad[id] = true
+
+	return false, nil
+}
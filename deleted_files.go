@@ -0,0 +1,84 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+)
+
+// objectsFromDeletedFiles emits the files deleted by commit, relative to its
+// first parent, with the content they had right before being deleted. When
+// pathspec is set, only deletions below it are emitted.
+func objectsFromDeletedFiles(repo *git.Repository, commit *git.Commit, pathspec []string, collector *objectCollector) ([]models.Object, error) {
+	var objectList []models.Object
+
+	if commit.ParentCount() == 0 {
+		return nil, nil
+	}
+
+	parent := commit.Parent(0)
+	if parent == nil {
+		// Parent not available (e.g. shallow clone boundary).
+		return nil, nil
+	}
+	defer parent.Free()
+
+	parentTree, err := parent.Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer parentTree.Free()
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Free()
+
+	diff, err := repo.DiffTreeToTree(parentTree, tree, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer diff.Free()
+
+	count, err := diff.NumDeltas()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < count; i++ {
+		delta, err := diff.GetDelta(i)
+		if err != nil {
+			return nil, err
+		}
+
+		if delta.Status != git.DeltaDeleted {
+			continue
+		}
+
+		if len(pathspec) > 0 && !matchPathspec(delta.OldFile.Path, pathspec) || collector.skippedBlob(delta.OldFile.Oid.String()) {
+			continue
+		}
+		if collector.excludedPath(delta.OldFile.Path) && !collector.includedBlob(delta.OldFile.Oid.String()) {
+			continue
+		}
+
+		blob, err := repo.LookupBlob(delta.OldFile.Oid)
+		if err != nil {
+			err = collector.corrupt(delta.OldFile.Oid.String(), delta.OldFile.Path, parent.Id().String(), err)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		o := models.NewObject(delta.OldFile.Path, Type, "file-content", blob.Contents())
+		o.SetMetadata("commit", parent.Id().String(), models.MetadataAttributes{})
+		o.SetMetadata("deleted-in", commit.Id().String(), models.MetadataAttributes{})
+		setUniqId(o, delta.OldFile.Oid.String())
+		objectList = append(objectList, *o)
+
+		blob.Free()
+	}
+
+	return objectList, nil
+}
@@ -0,0 +1,60 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// diffFirstParent diffs commit against its first parent, or against the empty
+// tree for root commits and commits whose parent is not available.
+func diffFirstParent(repo *git.Repository, commit *git.Commit, opts *git.DiffOptions) (*git.Diff, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Free()
+
+	var parentTree *git.Tree
+	if commit.ParentCount() > 0 {
+		if parent := commit.Parent(0); parent != nil {
+			parentTree, err = parent.Tree()
+			parent.Free()
+			if err != nil {
+				return nil, err
+			}
+			defer parentTree.Free()
+		}
+	}
+
+	return repo.DiffTreeToTree(parentTree, tree, opts)
+}
+
+// changedPaths returns the paths changed by commit relative to its first
+// parent. Renames are reported under their new path.
+func changedPaths(repo *git.Repository, commit *git.Commit) ([]string, error) {
+	diff, err := diffFirstParent(repo, commit, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer diff.Free()
+
+	count, err := diff.NumDeltas()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		delta, err := diff.GetDelta(i)
+		if err != nil {
+			return nil, err
+		}
+
+		if delta.Status == git.DeltaDeleted {
+			paths = append(paths, delta.OldFile.Path)
+		} else {
+			paths = append(paths, delta.NewFile.Path)
+		}
+	}
+
+	return paths, nil
+}
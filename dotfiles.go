@@ -0,0 +1,26 @@
+package sourcegit
+
+import (
+	"strings"
+)
+
+// isDotPath reports whether p is a dotfile or is inside a dotfile directory
+// (.github, .circleci, .vscode, ...), where configuration holding tokens
+// tends to hide.
+func isDotPath(p string) bool {
+	_, ok := dotPathParent(p)
+	return ok
+}
+
+// dotPathParent returns the directory holding the first dotfile or dotfile
+// directory of p, "" at the top level, and whether p has one.
+func dotPathParent(p string) (string, bool) {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if len(part) > 1 && part[0] == '.' && part != ".." {
+			return strings.Join(parts[:i], "/"), true
+		}
+	}
+
+	return "", false
+}
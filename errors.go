@@ -0,0 +1,90 @@
+package sourcegit
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kinds of load failures callers can react to with errors.Is. The errors
+// returned are *SourceError values wrapping the underlying error.
+var (
+	ErrAuthFailed     = errors.New("authentication failed")
+	ErrRepoNotFound   = errors.New("repository not found")
+	ErrNetworkTimeout = errors.New("network timeout")
+	ErrNotAGitRepo    = errors.New("not a git repository")
+	ErrEmptyRepo      = errors.New("repository is empty")
+	// The remote redirected, with strict-redirects.
+	ErrUnexpectedRedirect = errors.New("redirect to another host")
+	// The objects of the repository are in a hash algorithm libgit2 cannot
+	// read, where the git CLI cannot stand in for it (bundles, remotes).
+	ErrUnsupportedObjectFormat = errors.New("object format not supported")
+)
+
+// SourceError is a failure to load a source, of one of the kinds above.
+type SourceError struct {
+	Kind   error
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s: %v", e.Source, e.Kind)
+	}
+
+	return fmt.Sprintf("%s: %v: %v", e.Source, e.Kind, e.Err)
+}
+
+// Is makes errors.Is(err, ErrAuthFailed) and the like work.
+func (e *SourceError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the underlying error, usually a *git.GitError.
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// httpStatusRegexp matches the HTTP status of failed requests in the
+// messages of libgit2 ("unexpected HTTP status code: 404") and of the git CLI
+// ("The requested URL returned error: 404").
+var httpStatusRegexp = regexp.MustCompile(`(?:returned error|status code): (\d{3})\b`)
+
+// remoteError gives a kind to the failure to clone or fetch a remote, when
+// libgit2 tells enough to know it: by its error code, else by the HTTP
+// status or the message of the failure.
+func remoteError(source string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := strings.ToLower(err.Error())
+	status := 0
+	if m := httpStatusRegexp.FindStringSubmatch(msg); m != nil {
+		status, _ = strconv.Atoi(m[1])
+	}
+
+	var kind error
+	switch {
+	case isAuthError(err):
+		kind = ErrAuthFailed
+	case isNotFound(err):
+		kind = ErrRepoNotFound
+	case status == 401 || status == 403 || strings.Contains(msg, "authentication"):
+		kind = ErrAuthFailed
+	case status == 404 || strings.Contains(msg, "not found") || strings.Contains(msg, "does not exist"):
+		kind = ErrRepoNotFound
+	case strings.Contains(msg, "timed out") || strings.Contains(msg, "timeout"):
+		kind = ErrNetworkTimeout
+	// Redirects are only answered as errors when not followed.
+	case status >= 300 && status < 400:
+		kind = ErrUnexpectedRedirect
+	default:
+		return err
+	}
+
+	return &SourceError{Kind: kind, Source: source, Err: err}
+}
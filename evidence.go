@@ -0,0 +1,202 @@
+package sourcegit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/apuigsech/seekret"
+	"github.com/apuigsech/seekret/models"
+)
+
+// Version of the package recorded in evidence files. Release builds set it
+// with -ldflags "-X github.com/apuigsech/seekret-source-git.Version=...".
+var Version = "dev"
+
+// Options never written to an evidence file.
+var evidenceSecretOptions = map[string]bool{
+	"github-token": true,
+	"evidence-key": true,
+	"hash-key":     true,
+}
+
+// evidence records what a load scanned, to be written to evidence-file once
+// it completes.
+type evidence struct {
+	path   string
+	key    string
+	state  evidenceState
+	digest hash.Hash
+}
+
+type evidenceState struct {
+	Source  string            `json:"source"`
+	Tool    string            `json:"tool"`
+	Version string            `json:"version"`
+	Options map[string]string `json:"options"`
+	Refs    map[string]string `json:"refs"`
+	Commits []string          `json:"commits"`
+	Objects int               `json:"objects"`
+	// Hex SHA-256 over the type, name and content of every object returned
+	// or emitted, in order.
+	ObjectsDigest string    `json:"objects_digest"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// evidenceFile is what goes to disk: the state, its SHA-256 and its
+// HMAC-SHA256 with evidence-key. Any change to the state invalidates them.
+type evidenceFile struct {
+	Evidence  json.RawMessage `json:"evidence"`
+	Digest    string          `json:"digest"`
+	Signature string          `json:"signature"`
+}
+
+func newEvidence(opt SourceGitLoadOptions, source string, opta seekret.LoadOptions) *evidence {
+	options := make(map[string]string)
+	for k, v := range opta {
+		if !evidenceSecretOptions[k] {
+			options[k] = fmt.Sprint(v)
+		}
+	}
+
+	return &evidence{
+		path: opt.EvidenceFile,
+		key:  opt.EvidenceKey,
+		state: evidenceState{
+			Source:  source,
+			Tool:    "seekret-source-git",
+			Version: Version,
+			Options: options,
+			Refs:    make(map[string]string),
+			Commits: []string{},
+		},
+		digest: sha256.New(),
+	}
+}
+
+// refs records the tips the history walk starts from.
+func (e *evidence) refs(refs []scanRef) {
+	if e == nil {
+		return
+	}
+
+	for _, ref := range refs {
+		e.state.Refs[ref.Name] = ref.Target.String()
+	}
+}
+
+// commit records commit as scanned.
+func (e *evidence) commit(id string) {
+	if e == nil {
+		return
+	}
+
+	e.state.Commits = append(e.state.Commits, id)
+}
+
+// object records o as emitted, for the loads handing objects out as they
+// go instead of returning them.
+func (e *evidence) object(o *models.Object) error {
+	if e == nil {
+		return nil
+	}
+
+	content, err := OpenObjectContent(o)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	c := sha256.New()
+	if _, err := io.Copy(c, content); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.digest, "%s %s %x\n", o.SubType, o.Name, c.Sum(nil))
+	e.state.Objects++
+
+	return nil
+}
+
+// write completes the evidence with the objects returned and saves it.
+func (e *evidence) write(objectList []models.Object) error {
+	if e == nil {
+		return nil
+	}
+
+	for i := range objectList {
+		if err := e.object(&objectList[i]); err != nil {
+			return err
+		}
+	}
+	e.state.ObjectsDigest = hex.EncodeToString(e.digest.Sum(nil))
+	e.state.CreatedAt = time.Now().UTC()
+	sort.Strings(e.state.Commits)
+
+	state, err := json.Marshal(e.state)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(state)
+	mac := hmac.New(sha256.New, []byte(e.key))
+	mac.Write(state)
+	file := evidenceFile{
+		Evidence:  state,
+		Digest:    hex.EncodeToString(sum[:]),
+		Signature: hex.EncodeToString(mac.Sum(nil)),
+	}
+
+	// Indenting would also reformat the state and break the digest.
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	tmp := e.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, e.path)
+}
+
+// VerifyEvidence checks that the evidence file at path has not been altered
+// since it was written, using key, the evidence-key it was signed with.
+func VerifyEvidence(path string, key string) error {
+	if key == "" {
+		return fmt.Errorf("evidence %s cannot be verified without its key", path)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file evidenceFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid evidence %s: %v", path, err)
+	}
+
+	sum := sha256.Sum256(file.Evidence)
+	if hex.EncodeToString(sum[:]) != file.Digest {
+		return fmt.Errorf("evidence %s does not match its digest", path)
+	}
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(file.Evidence)
+	signature, err := hex.DecodeString(file.Signature)
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return fmt.Errorf("evidence %s does not match its signature", path)
+	}
+
+	return nil
+}
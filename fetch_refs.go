@@ -0,0 +1,112 @@
+package sourcegit
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// fetchRefs fetches opt.FetchRefs from opt.FetchRemote into repo, without
+// touching the working tree, and returns scan refs covering exactly the
+// commits that were not reachable from any ref before the fetch.
+func fetchRefs(repo *git.Repository, opt SourceGitLoadOptions) ([]scanRef, error) {
+	known, err := refTips(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := repo.Remotes.Lookup(opt.FetchRemote)
+	if err != nil {
+		return nil, err
+	}
+	defer remote.Free()
+
+	callbacks := newRemoteCallbacks("")
+	if opt.MaxBandwidth > 0 {
+		throttleCallbacks(&callbacks, opt.MaxBandwidth)
+	}
+	deadlineCallbacks(&callbacks, opt.deadline)
+
+	err = remote.Fetch(opt.FetchRefs, &git.FetchOptions{
+		RemoteCallbacks: callbacks,
+		UpdateFetchhead: true,
+	}, "")
+	if err != nil {
+		if pastDeadline(opt.deadline) {
+			return nil, fmt.Errorf("fetching from %s: %v", opt.FetchRemote, errOutOfTime)
+		}
+		return nil, err
+	}
+
+	fetched, err := readFetchHead(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range fetched {
+		fetched[i].Hide = known
+	}
+
+	return fetched, nil
+}
+
+// refTips returns the commits all refs of repo point to.
+func refTips(repo *git.Repository) ([]*git.Oid, error) {
+	iter, err := repo.NewReferenceIterator()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Free()
+
+	var tips []*git.Oid
+	for {
+		ref, err := iter.Next()
+		if err != nil {
+			if isIterOver(err) {
+				break
+			}
+			return nil, err
+		}
+
+		if ref.Type() == git.ReferenceOid {
+			if obj, err := ref.Peel(git.ObjectCommit); err == nil {
+				tips = append(tips, obj.Id())
+				obj.Free()
+			}
+		}
+		ref.Free()
+	}
+
+	return tips, nil
+}
+
+// readFetchHead parses FETCH_HEAD ("<oid>\t[not-for-merge]\t<description>").
+func readFetchHead(repo *git.Repository) ([]scanRef, error) {
+	fh, err := os.Open(filepath.Join(repo.Path(), "FETCH_HEAD"))
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var refs []scanRef
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		oid, err := git.NewOid(fields[0])
+		if err != nil {
+			continue
+		}
+
+		refs = append(refs, scanRef{Name: fields[2], Target: oid})
+	}
+
+	return refs, scanner.Err()
+}
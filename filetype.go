@@ -0,0 +1,138 @@
+package sourcegit
+
+import (
+	"bytes"
+	"path"
+	"strings"
+)
+
+// fileTypeNames classifies files by their whole name.
+var fileTypeNames = map[string]string{
+	"dockerfile":    "dockerfile",
+	"containerfile": "dockerfile",
+	"jenkinsfile":   "jenkinsfile",
+	"makefile":      "makefile",
+	"vagrantfile":   "ruby",
+	"gemfile":       "ruby",
+	"rakefile":      "ruby",
+	".env":          "dotenv",
+	".npmrc":        "ini",
+	".pypirc":       "ini",
+	".gitconfig":    "ini",
+	".netrc":        "netrc",
+	".htpasswd":     "htpasswd",
+	"id_rsa":        "private-key",
+	"id_dsa":        "private-key",
+	"id_ecdsa":      "private-key",
+	"id_ed25519":    "private-key",
+}
+
+// fileTypeExtensions classifies files by their extension.
+var fileTypeExtensions = map[string]string{
+	".yaml":       "yaml",
+	".yml":        "yaml",
+	".json":       "json",
+	".tf":         "terraform",
+	".tfvars":     "terraform",
+	".hcl":        "hcl",
+	".sh":         "shell",
+	".bash":       "shell",
+	".zsh":        "shell",
+	".ps1":        "powershell",
+	".py":         "python",
+	".rb":         "ruby",
+	".js":         "javascript",
+	".mjs":        "javascript",
+	".cjs":        "javascript",
+	".ts":         "typescript",
+	".go":         "go",
+	".java":       "java",
+	".kt":         "kotlin",
+	".groovy":     "groovy",
+	".gradle":     "groovy",
+	".php":        "php",
+	".cs":         "csharp",
+	".xml":        "xml",
+	".properties": "properties",
+	".ini":        "ini",
+	".cfg":        "ini",
+	".conf":       "ini",
+	".toml":       "toml",
+	".sql":        "sql",
+	".md":         "markdown",
+	".pem":        "pem",
+	".key":        "pem",
+	".crt":        "pem",
+	".p12":        "pkcs12",
+	".pfx":        "pkcs12",
+	".dockerfile": "dockerfile",
+}
+
+// fileTypeInterpreters classifies scripts by the interpreter of their
+// shebang line.
+var fileTypeInterpreters = map[string]string{
+	"sh":      "shell",
+	"bash":    "shell",
+	"zsh":     "shell",
+	"dash":    "shell",
+	"ksh":     "shell",
+	"python":  "python",
+	"python2": "python",
+	"python3": "python",
+	"ruby":    "ruby",
+	"node":    "javascript",
+	"perl":    "perl",
+	"php":     "php",
+}
+
+// fileType classifies a file by its name, or by its shebang line when the
+// name says nothing. It returns "" for unknown types.
+func fileType(name string, content []byte) string {
+	base := strings.ToLower(path.Base(name))
+
+	if t, ok := fileTypeNames[base]; ok {
+		return t
+	}
+	switch {
+	case strings.HasPrefix(base, "dockerfile."):
+		return "dockerfile"
+	case strings.HasPrefix(base, "docker-compose") && (strings.HasSuffix(base, ".yml") || strings.HasSuffix(base, ".yaml")):
+		return "docker-compose"
+	case strings.HasPrefix(base, ".env."):
+		return "dotenv"
+	}
+	if t, ok := fileTypeExtensions[path.Ext(base)]; ok {
+		return t
+	}
+
+	return shebangType(content)
+}
+
+// shebangType returns the type of a script from its "#!" line, following
+// "/usr/bin/env" to the actual interpreter.
+func shebangType(content []byte) string {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return ""
+	}
+
+	line := content[2:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				interpreter = f
+				break
+			}
+		}
+	}
+
+	return fileTypeInterpreters[interpreter]
+}
@@ -0,0 +1,69 @@
+package sourcegit
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+var scpLikeUrl = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.*)$`)
+
+// repositoryFingerprint returns a stable identifier of the repository, the
+// same for every clone of it: the normalized URL of the origin remote
+// ("url:github.com/owner/repo") or, without one, the root commit reached
+// following first parents from HEAD ("root:<sha>").
+func repositoryFingerprint(repo *git.Repository) (string, error) {
+	if remote, err := repo.Remotes.Lookup("origin"); err == nil {
+		url := remote.Url()
+		remote.Free()
+		if url != "" {
+			return "url:" + normalizeRemoteUrl(url), nil
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	defer head.Free()
+
+	commit, err := repo.LookupCommit(head.Target())
+	if err != nil {
+		return "", err
+	}
+
+	for commit.ParentCount() > 0 {
+		parent := commit.Parent(0)
+		commit.Free()
+		if parent == nil {
+			// Shallow clone without a remote: the root is out of reach.
+			return "", nil
+		}
+		commit = parent
+	}
+	defer commit.Free()
+
+	return "root:" + commit.Id().String(), nil
+}
+
+// normalizeRemoteUrl reduces the different spellings of a remote URL
+// (scheme, credentials, port-less scp-like syntax, ".git" suffix, case of
+// the host) to "host/path".
+func normalizeRemoteUrl(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+		if at := strings.LastIndex(strings.SplitN(url, "/", 2)[0], "@"); at >= 0 {
+			url = url[at+1:]
+		}
+	} else if u := scpLikeUrl.FindStringSubmatch(url); u != nil {
+		url = u[1] + "/" + u[2]
+	}
+
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+
+	parts := strings.SplitN(url, "/", 2)
+	parts[0] = strings.ToLower(parts[0])
+
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,110 @@
+package sourcegit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+type githubFork struct {
+	FullName string `json:"full_name"`
+	CloneUrl string `json:"clone_url"`
+}
+
+// forkRefs fetches the branches of every fork of the GitHub repository
+// cloned into repo under refs/forks/<owner>/<name>/, and returns scan refs
+// covering only the commits the forks do not share with upstream. Forks that
+// cannot be fetched are reported as warnings.
+func forkRefs(repo *git.Repository, opt SourceGitLoadOptions, report *LoadReport) ([]scanRef, error) {
+	upstream, err := githubRepository(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := refTips(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var forks []githubFork
+	err = newGithubClient(opt, report).each(fmt.Sprintf("/repos/%s/forks", upstream), func(item json.RawMessage) error {
+		var fork githubFork
+		if err := json.Unmarshal(item, &fork); err != nil {
+			return err
+		}
+		forks = append(forks, fork)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []scanRef
+	for _, fork := range forks {
+		namespace := "refs/forks/" + fork.FullName + "/"
+
+		if err := fetchFork(repo, fork.CloneUrl, namespace); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("fork %s: %v", fork.FullName, err))
+			continue
+		}
+
+		iter, err := repo.NewReferenceIteratorGlob(namespace + "*")
+		if err != nil {
+			return nil, err
+		}
+		for {
+			ref, err := iter.Next()
+			if err != nil {
+				if isIterOver(err) {
+					break
+				}
+				iter.Free()
+				return nil, err
+			}
+
+			refs = append(refs, scanRef{
+				Name:   "refs/heads/" + strings.TrimPrefix(ref.Name(), namespace),
+				Target: ref.Target(),
+				Hide:   known,
+				Fork:   fork.FullName,
+			})
+			ref.Free()
+		}
+		iter.Free()
+	}
+
+	return refs, nil
+}
+
+// fetchFork fetches the branches of a fork into namespace.
+func fetchFork(repo *git.Repository, url string, namespace string) error {
+	remote, err := repo.Remotes.CreateAnonymous(url)
+	if err != nil {
+		return err
+	}
+	defer remote.Free()
+
+	return remote.Fetch([]string{"+refs/heads/*:" + namespace + "*"}, &git.FetchOptions{
+		RemoteCallbacks: newRemoteCallbacks(""),
+	}, "")
+}
+
+// githubRepository returns the "owner/name" of the GitHub repository the
+// origin remote of repo points to.
+func githubRepository(repo *git.Repository) (string, error) {
+	remote, err := repo.Remotes.Lookup("origin")
+	if err != nil {
+		return "", err
+	}
+	url := normalizeRemoteUrl(remote.Url())
+	remote.Free()
+
+	parts := strings.SplitN(url, "/", 2)
+	if len(parts) != 2 || parts[0] != "github.com" {
+		return "", fmt.Errorf("%s is not a GitHub repository", url)
+	}
+
+	return parts[1], nil
+}
@@ -0,0 +1,90 @@
+package sourcegit
+
+import (
+	"bytes"
+	"path"
+	"strings"
+)
+
+// generatedNames are lock files and checksum databases, recognized by name.
+var generatedNames = map[string]bool{
+	"package-lock.json":   true,
+	"npm-shrinkwrap.json": true,
+	"yarn.lock":           true,
+	"pnpm-lock.yaml":      true,
+	"go.sum":              true,
+	"cargo.lock":          true,
+	"composer.lock":       true,
+	"gemfile.lock":        true,
+	"poetry.lock":         true,
+	"pipfile.lock":        true,
+}
+
+// generatedSuffixes are the names of generated code, minified assets and
+// source maps.
+var generatedSuffixes = []string{
+	".pb.go", ".pb.gw.go", "_pb2.py", "_pb2_grpc.py", ".pb.cc", ".pb.h",
+	".min.js", ".min.css", ".js.map", ".css.map",
+}
+
+// generatedMarkers are the comments tools put in the files they generate.
+var generatedMarkers = [][]byte{
+	[]byte("Code generated"),
+	[]byte("@generated"),
+}
+
+// generatedPath reports whether p is a generated file judging by its name,
+// or matches generated-patterns.
+func (c *objectCollector) generatedPath(p string) bool {
+	if len(c.opt.GeneratedPatterns) > 0 && matchPathspec(p, c.opt.GeneratedPatterns) {
+		return true
+	}
+
+	base := strings.ToLower(path.Base(p))
+	if generatedNames[base] {
+		return true
+	}
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// generated reports whether the file p with content was generated: by its
+// name, by a generator comment near the top, or, for JavaScript and CSS, by
+// lines too long to have been written by hand.
+func (c *objectCollector) generated(p string, content []byte) bool {
+	if c.generatedPath(p) {
+		return true
+	}
+
+	head := content
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	for _, marker := range generatedMarkers {
+		if bytes.Contains(head, marker) {
+			return true
+		}
+	}
+
+	switch path.Ext(strings.ToLower(p)) {
+	case ".js", ".mjs", ".css":
+		return minified(content)
+	}
+
+	return false
+}
+
+// minified reports whether content has an average line length over 500
+// characters.
+func minified(content []byte) bool {
+	if len(content) < 2048 {
+		return false
+	}
+
+	return len(content)/(bytes.Count(content, []byte("\n"))+1) > 500
+}
@@ -0,0 +1,82 @@
+package sourcegit
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/apuigsech/seekret"
+	"github.com/apuigsech/seekret/models"
+	"regexp"
+)
+
+var (
+	gistRegexp     = regexp.MustCompile(`^(?:https?://)?gist\.github\.com/(?:[^/]+/)?([0-9a-f]{20,32})(?:\.git)?/?$`)
+	gistUserRegexp = regexp.MustCompile(`^(?:https?://)?gist\.github\.com/([^/]+)/?$`)
+)
+
+// gistCloneUrl returns the clone URL of a gist given the URL of its page
+// (https://gist.github.com/<user>/<id>) or of its repository.
+func gistCloneUrl(source string) (string, bool) {
+	u := gistRegexp.FindStringSubmatch(source)
+	if u == nil {
+		return "", false
+	}
+
+	return fmt.Sprintf("https://gist.github.com/%s.git", u[1]), true
+}
+
+// gistUser returns the user of a gist listing URL
+// (https://gist.github.com/<user>), whose gists are all scanned.
+func gistUser(source string) (string, bool) {
+	if _, ok := gistCloneUrl(source); ok {
+		return "", false
+	}
+
+	u := gistUserRegexp.FindStringSubmatch(source)
+	if u == nil {
+		return "", false
+	}
+
+	return u[1], true
+}
+
+type githubGist struct {
+	Id         string `json:"id"`
+	GitPullUrl string `json:"git_pull_url"`
+}
+
+// loadUserGists scans every public gist of user, as listed by the GitHub
+// API. Objects carry the id of their gist in the "gist" metadata; gists that
+// cannot be cloned are reported as warnings.
+func (s *SourceGit) loadUserGists(user string, opt SourceGitLoadOptions, opta seekret.LoadOptions) ([]models.Object, *LoadReport, error) {
+	var objectList []models.Object
+	report := &LoadReport{}
+
+	var gists []githubGist
+	err := newGithubClient(opt, report).each(fmt.Sprintf("/users/%s/gists", user), func(item json.RawMessage) error {
+		var gist githubGist
+		if err := json.Unmarshal(item, &gist); err != nil {
+			return err
+		}
+		gists = append(gists, gist)
+		return nil
+	})
+	if err != nil {
+		return nil, report, err
+	}
+
+	for _, gist := range gists {
+		gistObjects, gistReport, err := s.LoadObjectsWithReport(gist.GitPullUrl, subSourceOptions(opta))
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("gist %s: %v", gist.Id, err))
+			continue
+		}
+		report.merge(gistReport)
+
+		for i := range gistObjects {
+			gistObjects[i].SetMetadata("gist", gist.Id, models.MetadataAttributes{})
+		}
+		objectList = append(objectList, gistObjects...)
+	}
+
+	return objectList, report, nil
+}
@@ -0,0 +1,54 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// This file names the error codes, credential constructors and callback
+// return values used across the package. They differ between the majors of
+// git2go, which only internal/git imports: see its doc for the build tags
+// selecting v26, v33 or v34.
+
+// callbackOk is what libgit2 callbacks return to go on.
+var callbackOk = git.CallbackOk
+
+// callbackAbort is what libgit2 callbacks return to abort the operation.
+var callbackAbort = git.CallbackAbort
+
+// openFromEnv makes OpenRepositoryExtended honor GIT_DIR, GIT_WORK_TREE,
+// GIT_INDEX_FILE and the rest of the environment git itself reads.
+const openFromEnv = git.RepositoryOpenFromEnv
+
+func isIterOver(err error) bool {
+	return git.IsErrorCode(err, git.ErrorCodeIterOver)
+}
+
+func isNotFound(err error) bool {
+	return git.IsErrorCode(err, git.ErrorCodeNotFound)
+}
+
+func isUnbornBranch(err error) bool {
+	return git.IsErrorCode(err, git.ErrorCodeUnbornBranch)
+}
+
+func isAuthError(err error) bool {
+	return git.IsErrorCode(err, git.ErrorCodeAuth)
+}
+
+// userpassCredential is the result of a credentials callback offering a
+// username and password.
+func userpassCredential(user, pass string) (*git.Credential, error) {
+	return git.NewCredentialUserpassPlaintext(user, pass)
+}
+
+// sshKeyCredential is the result of a credentials callback offering an SSH
+// key pair.
+func sshKeyCredential(user, publicKey, privateKey, passphrase string) (*git.Credential, error) {
+	return git.NewCredentialSSHKey(user, publicKey, privateKey, passphrase)
+}
+
+// noCredential is the result of a credentials callback with nothing left to
+// offer.
+func noCredential() (*git.Credential, error) {
+	return nil, &git.GitError{Message: "no credentials left to offer", Code: git.ErrorCodeAuth}
+}
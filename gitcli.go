@@ -0,0 +1,38 @@
+package sourcegit
+
+import (
+	"os"
+	"os/exec"
+)
+
+// untrustedRepoConfig overrides what the configuration of a scanned
+// repository, which may be hostile, could make the git CLI run: hooks
+// (reference-transaction runs on fetch), fsmonitor, ssh commands, credential
+// helpers and askpass programs, and arbitrary remote transports. The only
+// fetch made in a scanned repository (partial_clone.go) also overrides the
+// upload-pack command of its remote. libgit2 itself never runs hooks,
+// fsmonitor nor external filter drivers (smudge, clean, textconv).
+var untrustedRepoConfig = []string{
+	"-c", "core.hooksPath=" + os.DevNull,
+	"-c", "core.fsmonitor=false",
+	"-c", "core.sshCommand=ssh",
+	"-c", "core.askPass=",
+	"-c", "credential.helper=",
+	"-c", "protocol.ext.allow=never",
+}
+
+// gitCommand prepares an invocation of the git CLI. It is only used where
+// libgit2 has no equivalent, and never prompts on the terminal. Commands
+// run in dir, a scanned repository, cannot run anything it configures.
+func gitCommand(dir string, args ...string) *exec.Cmd {
+	if dir != "" {
+		args = append(append([]string(nil), untrustedRepoConfig...), args...)
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Env = append(cmd.Env, isolatedConfigEnv()...)
+
+	return cmd
+}
@@ -0,0 +1,104 @@
+package sourcegit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apuigsech/seekret"
+)
+
+// hostileRepo creates a repository whose configuration runs a command
+// creating marker for every filter, hook, credential helper and askpass
+// program git could call, once its content is committed.
+func hostileRepo(t *testing.T) (dir string, marker string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "sourcegit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	marker = filepath.Join(dir, "pwned")
+	testGit(t, dir, "init", "--quiet")
+	for name, content := range map[string]string{
+		".gitattributes": "* filter=evil diff=evil\n",
+		"secret.txt":     "password=hunter2\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testGit(t, dir, "add", ".")
+	testGit(t, dir, "commit", "--quiet", "-m", "initial")
+
+	hooks := filepath.Join(dir, "hooks")
+	if err := os.Mkdir(hooks, 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "evil.sh")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\ntouch "+marker+"\ncat\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, hook := range []string{"reference-transaction", "post-checkout", "post-index-change"} {
+		if err := os.Symlink(script, filepath.Join(hooks, hook)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, kv := range [][2]string{
+		{"filter.evil.smudge", script},
+		{"filter.evil.clean", script},
+		{"filter.evil.process", script},
+		{"diff.evil.textconv", script},
+		{"core.hooksPath", hooks},
+		{"core.fsmonitor", script},
+		{"core.askPass", script},
+		{"credential.helper", "!" + script},
+	} {
+		testGit(t, dir, "config", kv[0], kv[1])
+	}
+
+	return dir, marker
+}
+
+func TestHostileRepoFiltersNotRun(t *testing.T) {
+	dir, marker := hostileRepo(t)
+
+	objects, err := SourceTypeGit.LoadObjects(dir, seekret.LoadOptions{
+		"commit-files":     true,
+		"commit-diffs":     true,
+		"staged-files":     true,
+		"worktree-changes": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) == 0 {
+		t.Fatal("no objects loaded")
+	}
+
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("the configuration of the scanned repository ran a command")
+	}
+}
+
+func TestHostileRepoCommandsNotRun(t *testing.T) {
+	dir, marker := hostileRepo(t)
+
+	// Runs the reference-transaction hook, like fetches do.
+	if out, err := gitCommand(dir, "update-ref", "refs/heads/other", "HEAD").CombinedOutput(); err != nil {
+		t.Fatalf("git update-ref: %v\n%s", err, out)
+	}
+
+	cmd := gitCommand(dir, "credential", "fill")
+	cmd.Stdin = strings.NewReader("protocol=https\nhost=example.com\n\n")
+	cmd.Run()
+
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("the configuration of the scanned repository ran a command")
+	}
+}
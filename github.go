@@ -0,0 +1,169 @@
+package sourcegit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultGithubApiUrl = "https://api.github.com"
+
+var linkNextRegexp = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// githubClient is a minimal client of the GitHub REST API, for the features
+// that enumerate repositories or fetch content living outside of them.
+type githubClient struct {
+	apiUrl string
+	token  string
+	http   *http.Client
+
+	// Where the API quota left is published.
+	report *LoadReport
+}
+
+// newGithubClient returns a client of the API at github-api-url,
+// authenticated with github-token or, without it, $GITHUB_TOKEN. The quota
+// left is kept up to date in report.
+func newGithubClient(opt SourceGitLoadOptions, report *LoadReport) *githubClient {
+	c := &githubClient{
+		apiUrl: strings.TrimSuffix(opt.GithubApiUrl, "/"),
+		token:  opt.GithubToken,
+		http:   http.DefaultClient,
+		report: report,
+	}
+	if c.apiUrl == "" {
+		c.apiUrl = defaultGithubApiUrl
+	}
+	if c.token == "" {
+		c.token = os.Getenv("GITHUB_TOKEN")
+	}
+
+	return c
+}
+
+// get decodes the response to a GET of url (absolute, or a path relative to
+// the API) into v, and returns the url of the next page, if any.
+func (c *githubClient) get(url string, v interface{}) (string, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		url = c.apiUrl + url
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "token "+c.token)
+	}
+
+	var resp *http.Response
+	var body []byte
+	for {
+		resp, err = c.http.Do(req)
+		if err != nil {
+			return "", err
+		}
+		body, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", err
+		}
+
+		wait, limited := c.rateLimit(resp)
+		time.Sleep(wait)
+		if !limited {
+			break
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub API %s: %s: %s", url, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return "", err
+	}
+
+	if m := linkNextRegexp.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+		return m[1], nil
+	}
+
+	return "", nil
+}
+
+// rateLimit records the quota left after resp and tells how long to wait
+// before the next request. Requests are paced so that the quota lasts until
+// it is reset, and a request refused for exceeding the primary or secondary
+// rate limit (limited) is retried once the limit is lifted.
+func (c *githubClient) rateLimit(resp *http.Response) (time.Duration, bool) {
+	remaining, errRemaining := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	reset, errReset := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+
+	var until time.Duration
+	if errReset == nil {
+		until = time.Until(time.Unix(reset, 0))
+		if until < 0 {
+			until = 0
+		}
+	}
+
+	if errRemaining == nil && c.report != nil {
+		c.report.RateLimitRemaining = remaining
+		if errReset == nil {
+			c.report.RateLimitReset = time.Unix(reset, 0)
+		}
+	}
+
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+		if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return time.Duration(retryAfter) * time.Second, true
+		}
+		if errRemaining == nil && remaining == 0 && errReset == nil {
+			return until + time.Second, true
+		}
+		return 0, false
+	}
+
+	// Below a tenth of the hourly quota, spread what is left until the reset.
+	if errRemaining == nil && errReset == nil && remaining < 500 {
+		return until / time.Duration(remaining+1), false
+	}
+
+	return 0, false
+}
+
+// each calls fn with every item of a paginated listing, following the
+// pages until the last one.
+func (c *githubClient) each(path string, fn func(item json.RawMessage) error) error {
+	url := path
+	if strings.Contains(url, "?") {
+		url += "&per_page=100"
+	} else {
+		url += "?per_page=100"
+	}
+
+	for url != "" {
+		var items []json.RawMessage
+
+		next, err := c.get(url, &items)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+
+		url = next
+	}
+
+	return nil
+}
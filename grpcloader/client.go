@@ -0,0 +1,52 @@
+package grpcloader
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/apuigsech/seekret"
+	sourcegit "github.com/apuigsech/seekret-source-git"
+	"github.com/apuigsech/seekret/models"
+	"google.golang.org/grpc"
+)
+
+// LoadObjects loads the objects of source on the Loader service behind conn,
+// calling fn with every object as it arrives. An error from fn cancels the
+// load.
+func LoadObjects(ctx context.Context, conn *grpc.ClientConn, source string, opta seekret.LoadOptions, fn func(o *models.Object) error) (*sourcegit.LoadReport, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[0], loadObjectsMethodName, grpc.CallContentSubtype(codecName))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := stream.SendMsg(&LoadRequest{Source: source, Options: opta}); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	for {
+		var resp LoadResponse
+		err := stream.RecvMsg(&resp)
+		if err == io.EOF {
+			return nil, errors.New("load ended without a report")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.Report != nil {
+			return resp.Report, nil
+		}
+		if resp.Object != nil {
+			if err := fn(resp.Object.Object()); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
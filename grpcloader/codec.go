@@ -0,0 +1,30 @@
+package grpcloader
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// codecName is the content-subtype of the Loader service: messages are JSON
+// documents rather than protocol buffers, so that no generated code is
+// needed on either side.
+const codecName = "json"
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return codecName
+}
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
@@ -0,0 +1,212 @@
+// Package grpcloader exposes the LoadObjects of a SourceGit over gRPC, so
+// that repositories are loaded on dedicated workers while rules are
+// evaluated elsewhere. Objects are streamed to the client as they are
+// emitted, followed by the load report:
+//
+//	g := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+//	grpcloader.NewServer(sourcegit.SourceTypeGit, grpcloader.Config{}).Register(g)
+//	g.Serve(listener)
+//
+// The service runs loads on behalf of its clients, so it must only be
+// reachable by trusted ones: serve it with transport credentials
+// (grpc.Creds with mutual TLS) or an authenticating interceptor. Clients
+// can only scan remote repositories, with the options of Config.
+//
+// On the client side:
+//
+//	report, err := grpcloader.LoadObjects(ctx, conn, source, opts, func(o *models.Object) error {
+//		...
+//	})
+package grpcloader
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+
+	"github.com/apuigsech/seekret"
+	sourcegit "github.com/apuigsech/seekret-source-git"
+	"github.com/apuigsech/seekret/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	serviceName           = "seekret.sourcegit.Loader"
+	loadObjectsMethodName = "/" + serviceName + "/LoadObjects"
+)
+
+// LoadRequest asks for the objects of Source, loaded with Options (the load
+// options of sourcegit).
+type LoadRequest struct {
+	Source  string                 `json:"source"`
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// LoadResponse carries one object, or, in the last response of a load, its
+// report.
+type LoadResponse struct {
+	Object *sourcegit.StreamRecord `json:"object,omitempty"`
+	Report *sourcegit.LoadReport   `json:"report,omitempty"`
+}
+
+// DefaultAllowedOptions are the load options clients may set when Config
+// does not say otherwise: what to scan and how to emit it. Options naming
+// files or directories of the worker, credentials, other servers (GitHub API)
+// or changing the worker itself are left to the operator. A profile is only
+// accepted when all of its options are allowed.
+var DefaultAllowedOptions = []string{
+	"profile",
+	"commit-files", "commit-messages", "split-commit-messages", "commit-metadata",
+	"commit-diffs", "diff-filetypes", "deleted-files", "staged-files",
+	"message-min-length", "message-noise", "message-noise-patterns", "mailmap",
+	"commit-count", "head-only", "all-branches", "refs", "default-branch-only",
+	"exclude-refs", "exclude-commits", "revision-range", "push-update",
+	"pathspec", "path-exclude", "path-exclude-dotfiles", "dotfiles-only",
+	"skip-blobs", "include-blobs", "known-secrets", "skip-generated",
+	"generated-patterns", "max-path-depth",
+	"incremental-content", "codeowners", "metadata-only", "type-names",
+	"type-prefix", "blob-lifetime", "at-head", "hash-only", "hash-shingle-size",
+	"sample-every-n", "sample-period", "dedup-patch-id", "untrusted-authors",
+	"ignore-revs", "oldest-first", "sort", "since", "until", "date-field",
+	"ref-commit-budget", "round-robin-refs", "max-duration",
+}
+
+// Config is what the operator of a Server lets its clients do.
+type Config struct {
+	// Load options clients may set, DefaultAllowedOptions when nil.
+	// Requests with any other option are rejected.
+	AllowedOptions []string
+	// Load options of every load, overriding those of the client, e.g.
+	// "github-token", "ca-bundle" or "spool-dir".
+	Options map[string]interface{}
+	// Let clients scan local paths and file:// URLs of the worker.
+	AllowLocalSources bool
+}
+
+// Server is the Loader service, loading from source.
+type Server struct {
+	source  *sourcegit.SourceGit
+	config  Config
+	allowed map[string]bool
+}
+
+func NewServer(source *sourcegit.SourceGit, config Config) *Server {
+	allowed := config.AllowedOptions
+	if allowed == nil {
+		allowed = DefaultAllowedOptions
+	}
+
+	s := &Server{
+		source:  source,
+		config:  config,
+		allowed: make(map[string]bool, len(allowed)),
+	}
+	for _, name := range allowed {
+		s.allowed[name] = true
+	}
+
+	return s
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "LoadObjects",
+			Handler:       loadObjectsHandler,
+			ServerStreams: true,
+		},
+	},
+}
+
+// Register adds the Loader service to g.
+func (s *Server) Register(g *grpc.Server) {
+	g.RegisterService(&serviceDesc, s)
+}
+
+func loadObjectsHandler(srv interface{}, stream grpc.ServerStream) error {
+	var req LoadRequest
+	if err := stream.RecvMsg(&req); err != nil {
+		return err
+	}
+
+	s := srv.(*Server)
+	opta, err := s.loadOptions(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	report, err := s.source.LoadObjectsFunc(req.Source, opta, func(o *models.Object) error {
+		record, err := sourcegit.NewStreamRecord(o)
+		if err != nil {
+			return err
+		}
+		return stream.SendMsg(&LoadResponse{Object: record})
+	})
+	if err != nil {
+		return err
+	}
+
+	return stream.SendMsg(&LoadResponse{Report: report})
+}
+
+// loadOptions checks req against the configuration of the server and
+// returns the options of its load. The whole numbers of options decoded from
+// JSON are turned back into the ints sourcegit expects.
+func (s *Server) loadOptions(req LoadRequest) (seekret.LoadOptions, error) {
+	if !s.config.AllowLocalSources && !remoteSource(req.Source) {
+		return nil, fmt.Errorf("source %q is not a remote repository", req.Source)
+	}
+
+	// The options a profile sets are checked too.
+	var denied []string
+	for k := range sourcegit.ApplyProfile(seekret.LoadOptions(req.Options)) {
+		if !s.allowed[k] {
+			denied = append(denied, k)
+		}
+	}
+	if len(denied) > 0 {
+		sort.Strings(denied)
+		return nil, fmt.Errorf("options not allowed: %v", denied)
+	}
+
+	opta := make(seekret.LoadOptions, len(req.Options)+len(s.config.Options))
+	for k, v := range req.Options {
+		if f, ok := v.(float64); ok && f == math.Trunc(f) {
+			v = int(f)
+		}
+		opta[k] = v
+	}
+	for k, v := range s.config.Options {
+		opta[k] = v
+	}
+
+	return opta, nil
+}
+
+var scpSourceRegexp = regexp.MustCompile(`^[A-Za-z0-9._~-]+@[A-Za-z0-9.-]+:[^-]`)
+
+// remoteSource reports whether source is the URL of a remote repository, over
+// a network transport, rather than a path or file:// URL of the worker.
+func remoteSource(source string) bool {
+	if scpSourceRegexp.MatchString(source) {
+		return true
+	}
+
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	switch u.Scheme {
+	case "https", "http", "ssh", "git":
+		return true
+	}
+
+	return false
+}
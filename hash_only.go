@@ -0,0 +1,59 @@
+package sourcegit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+
+	"github.com/apuigsech/seekret/models"
+)
+
+// hashOnly replaces the content of o with hashes, for hash-only: the
+// SHA-256 of the whole content goes to the "content-sha256" metadata, and
+// line n of the new content is the hash of the shingle of hash-shingle-size
+// lines starting at line n of the original, with surrounding whitespace
+// trimmed. Findings on hashes keep the line numbers of the original content.
+// With hash-key, hashes are HMAC-SHA256 so they cannot be reversed by
+// guessing.
+func (c *objectCollector) hashOnly(o *models.Object) {
+	if o.Content == nil {
+		return
+	}
+
+	newHash := sha256.New
+	if c.opt.HashKey != "" {
+		newHash = func() hash.Hash { return hmac.New(sha256.New, []byte(c.opt.HashKey)) }
+	}
+
+	h := newHash()
+	h.Write(o.Content)
+	o.SetMetadata("content-sha256", hex.EncodeToString(h.Sum(nil)), models.MetadataAttributes{})
+
+	size := c.opt.HashShingleSize
+	if size <= 0 {
+		size = 1
+	}
+
+	lines := bytes.Split(o.Content, []byte("\n"))
+	for i := range lines {
+		lines[i] = bytes.TrimSpace(lines[i])
+	}
+
+	var out bytes.Buffer
+	for i := range lines {
+		end := i + size
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		h := newHash()
+		h.Write(bytes.Join(lines[i:end], []byte("\n")))
+		out.WriteString(hex.EncodeToString(h.Sum(nil)))
+		out.WriteByte('\n')
+	}
+
+	o.Content = out.Bytes()
+	o.SetMetadata("hash-only", "true", models.MetadataAttributes{})
+}
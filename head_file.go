@@ -0,0 +1,40 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// headFile returns the content of the file at path in the tree of HEAD, or
+// nil when there is no such file (or no HEAD yet).
+func headFile(repo *git.Repository, path string) ([]byte, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return nil, nil
+	}
+	defer head.Free()
+
+	commit, err := repo.LookupCommit(head.Target())
+	if err != nil {
+		return nil, err
+	}
+	defer commit.Free()
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Free()
+
+	entry, err := tree.EntryByPath(path)
+	if err != nil || entry.Type != git.ObjectBlob {
+		return nil, nil
+	}
+
+	blob, err := repo.LookupBlob(entry.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Free()
+
+	return blob.Contents(), nil
+}
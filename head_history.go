@@ -0,0 +1,94 @@
+package sourcegit
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// BranchRename is a rename of a branch (e.g. master to main) recorded in the
+// reflog of HEAD.
+type BranchRename struct {
+	From string
+	To   string
+	When time.Time
+}
+
+// headRef returns the ref HEAD points to, or "" for a detached HEAD.
+func headRef(repo *git.Repository) string {
+	head, err := repo.References.Lookup("HEAD")
+	if err != nil {
+		return ""
+	}
+	defer head.Free()
+
+	return head.SymbolicTarget()
+}
+
+// branchRenames returns the branch renames in the reflog of HEAD, oldest
+// first. Fresh clones have no reflog to read them from.
+func branchRenames(repo *git.Repository) ([]BranchRename, error) {
+	f, err := os.Open(filepath.Join(repo.Path(), "logs", "HEAD"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var renames []BranchRename
+
+	// <old> <new> <name> <<email>> <time> <tz>\t<message>
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		if !strings.HasPrefix(strings.ToLower(fields[1]), "branch: renamed ") {
+			continue
+		}
+		parts := strings.Fields(fields[1][len("branch: renamed "):])
+		if len(parts) != 3 || parts[1] != "to" {
+			continue
+		}
+
+		var when time.Time
+		if sig := strings.Fields(fields[0]); len(sig) >= 2 {
+			if sec, err := strconv.ParseInt(sig[len(sig)-2], 10, 64); err == nil {
+				when = time.Unix(sec, 0).UTC()
+			}
+		}
+
+		renames = append(renames, BranchRename{From: parts[0], To: parts[2], When: when})
+	}
+
+	return renames, scanner.Err()
+}
+
+// formerNames returns the former names of the local branch, most recent
+// first, following renames back in time. Remote-tracking branches of origin
+// stand for the local branch of the same name.
+func formerNames(branch string, renames []BranchRename) []string {
+	if strings.HasPrefix(branch, "refs/remotes/origin/") {
+		branch = "refs/heads/" + strings.TrimPrefix(branch, "refs/remotes/origin/")
+	}
+
+	var names []string
+
+	for i := len(renames) - 1; i >= 0; i-- {
+		if renames[i].To == branch {
+			branch = renames[i].From
+			names = append(names, branch)
+		}
+	}
+
+	return names
+}
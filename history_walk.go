@@ -0,0 +1,400 @@
+package sourcegit
+
+import (
+	"bufio"
+	"container/heap"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// historyWalker walks the commits reachable from a scan ref. libgit2 knows
+// nothing about replace refs and only partially about shallow grafts, so
+// when either is in play the walk is done here instead of by libgit2.
+type historyWalker struct {
+	repo *git.Repository
+
+	// Original commit id -> replacement commit, from refs/replace/*.
+	replacements map[string]*git.Oid
+	// Replacement commit id -> original commit id.
+	replacedBy map[string]string
+
+	// Commits at the boundary of a shallow clone.
+	shallow      map[string]bool
+	ignoreGrafts bool
+
+	// Return commits oldest first, considering only the limit newest ones
+	// when limit is positive.
+	reverse bool
+	limit   int
+
+	// Never return a commit before its children.
+	topo bool
+	// Order by author time instead of committer time.
+	authorTime bool
+
+	// Commit id -> shortest distance in commits from the tip of the ref
+	// being walked.
+	depths map[string]int
+}
+
+func newHistoryWalker(repo *git.Repository, opt SourceGitLoadOptions, report *LoadReport) (*historyWalker, error) {
+	w := &historyWalker{
+		repo:         repo,
+		replacements: make(map[string]*git.Oid),
+		replacedBy:   make(map[string]string),
+		ignoreGrafts: opt.IgnoreGrafts,
+		reverse:      opt.OldestFirst,
+		limit:        opt.CommitCount,
+		topo:         opt.Sort == "topo",
+		authorTime:   opt.DateField == "author",
+	}
+
+	if !opt.NoReplaceObjects {
+		if err := w.loadReplaceRefs(); err != nil {
+			return nil, err
+		}
+	}
+	report.ReplaceRefs = len(w.replacements)
+
+	shallow, err := readShallowFile(repo)
+	if err != nil {
+		return nil, err
+	}
+	w.shallow = shallow
+	report.Shallow = len(shallow) > 0
+
+	return w, nil
+}
+
+func (w *historyWalker) loadReplaceRefs() error {
+	iter, err := w.repo.NewReferenceIteratorGlob("refs/replace/*")
+	if err != nil {
+		return err
+	}
+	defer iter.Free()
+
+	for {
+		ref, err := iter.Next()
+		if err != nil {
+			if isIterOver(err) {
+				break
+			}
+			return err
+		}
+
+		original := strings.TrimPrefix(ref.Name(), "refs/replace/")
+		if ref.Type() == git.ReferenceOid {
+			w.replacements[original] = ref.Target()
+			w.replacedBy[ref.Target().String()] = original
+		}
+		ref.Free()
+	}
+
+	return nil
+}
+
+// readShallowFile returns the boundary commits listed in .git/shallow.
+func readShallowFile(repo *git.Repository) (map[string]bool, error) {
+	shallow := make(map[string]bool)
+
+	fh, err := os.Open(filepath.Join(repo.Path(), "shallow"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return shallow, nil
+		}
+		return nil, err
+	}
+	defer fh.Close()
+
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			shallow[id] = true
+		}
+	}
+
+	return shallow, scanner.Err()
+}
+
+// walk calls fn for every commit reachable from ref, newest first (or
+// oldest first when reversed), until fn returns false.
+func (w *historyWalker) walk(ref scanRef, fn func(*git.Commit) bool) error {
+	w.depths = make(map[string]int)
+
+	if w.reverse {
+		return w.walkReverse(ref, fn)
+	}
+
+	return w.walkForward(ref, fn)
+}
+
+// walkReverse collects the commits newest first, honoring the limit, and
+// replays them in the opposite order.
+func (w *historyWalker) walkReverse(ref scanRef, fn func(*git.Commit) bool) error {
+	var ids []*git.Oid
+
+	err := w.walkForward(ref, func(commit *git.Commit) bool {
+		ids = append(ids, commit.Id())
+		return w.limit <= 0 || len(ids) < w.limit
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := len(ids) - 1; i >= 0; i-- {
+		commit, err := w.repo.LookupCommit(ids[i])
+		if err != nil {
+			return err
+		}
+
+		keepGoing := fn(commit)
+		commit.Free()
+		if !keepGoing {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (w *historyWalker) walkForward(ref scanRef, fn func(*git.Commit) bool) error {
+	// Children are walked before their parents (except across clock skew,
+	// where the depth may come out longer than the shortest path).
+	track := func(commit *git.Commit) bool {
+		depth := w.depths[commit.Id().String()]
+		for _, parent := range w.parents(commit) {
+			if d, ok := w.depths[parent.String()]; !ok || depth+1 < d {
+				w.depths[parent.String()] = depth + 1
+			}
+		}
+
+		return fn(commit)
+	}
+
+	// libgit2 only sorts by committer time.
+	if len(w.replacements) == 0 && len(w.shallow) == 0 && !w.authorTime {
+		return w.walkLibgit2(ref, track)
+	}
+
+	return w.walkGrafted(ref, track)
+}
+
+// depth returns the distance in commits from the tip of the ref being
+// walked to the commit, 0 being the tip itself.
+func (w *historyWalker) depth(commit *git.Commit) int {
+	return w.depths[commit.Id().String()]
+}
+
+func (w *historyWalker) walkLibgit2(ref scanRef, fn func(*git.Commit) bool) error {
+	walk, err := w.repo.Walk()
+	if err != nil {
+		return err
+	}
+	defer walk.Free()
+
+	err = walk.Push(ref.Target)
+	if err != nil {
+		return err
+	}
+
+	for _, hide := range ref.Hide {
+		err = walk.Hide(hide)
+		if err != nil {
+			return err
+		}
+	}
+	if w.topo {
+		walk.Sorting(git.SortTopological | git.SortTime)
+	} else {
+		walk.Sorting(git.SortTime)
+	}
+
+	return walk.Iterate(func(commit *git.Commit) bool {
+		defer commit.Free()
+		return fn(commit)
+	})
+}
+
+// walkGrafted walks history substituting replaced commits and stopping at
+// (or, with ignore-grafts, looking past) the shallow boundary, ordered by
+// author or committer time.
+func (w *historyWalker) walkGrafted(ref scanRef, fn func(*git.Commit) bool) error {
+	hidden := make(map[string]bool)
+	if len(ref.Hide) > 0 {
+		err := w.traverse(ref.Hide, func(commit *git.Commit) bool {
+			hidden[commit.Id().String()] = true
+			return true
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	if w.topo {
+		return w.traverseTopo([]*git.Oid{ref.Target}, fn, hidden)
+	}
+
+	return w.traverse([]*git.Oid{ref.Target}, fn, hidden)
+}
+
+// parents returns the parents of commit as seen by the walk: replaced
+// commits substituted and none past the shallow boundary.
+func (w *historyWalker) parents(commit *git.Commit) []*git.Oid {
+	if w.shallow[commit.Id().String()] && !w.ignoreGrafts {
+		return nil
+	}
+
+	parents := make([]*git.Oid, 0, commit.ParentCount())
+	for i := uint(0); i < commit.ParentCount(); i++ {
+		id := commit.ParentId(i)
+		if replacement, ok := w.replacements[id.String()]; ok {
+			id = replacement
+		}
+		parents = append(parents, id)
+	}
+
+	return parents
+}
+
+func (w *historyWalker) traverse(from []*git.Oid, fn func(*git.Commit) bool, hidden map[string]bool) error {
+	queue := &commitQueue{author: w.authorTime}
+	visited := make(map[string]bool)
+
+	push := func(id *git.Oid) {
+		if visited[id.String()] || hidden[id.String()] {
+			return
+		}
+		visited[id.String()] = true
+
+		commit, err := w.repo.LookupCommit(id)
+		if err != nil {
+			// Beyond what the object database has.
+			return
+		}
+		heap.Push(queue, commit)
+	}
+
+	for _, id := range from {
+		if replacement, ok := w.replacements[id.String()]; ok {
+			id = replacement
+		}
+		push(id)
+	}
+
+	// Commits still queued when fn stops the walk.
+	defer func() {
+		for _, commit := range queue.commits {
+			commit.Free()
+		}
+	}()
+
+	for queue.Len() > 0 {
+		commit := heap.Pop(queue).(*git.Commit)
+
+		for _, parent := range w.parents(commit) {
+			push(parent)
+		}
+
+		keepGoing := fn(commit)
+		commit.Free()
+		if !keepGoing {
+			break
+		}
+	}
+
+	return nil
+}
+
+// traverseTopo reads the whole history first so that every commit can be
+// held back until all its children have been returned. Among the commits
+// ready to go, the newest comes first.
+func (w *historyWalker) traverseTopo(from []*git.Oid, fn func(*git.Commit) bool, hidden map[string]bool) error {
+	var ids []*git.Oid
+	err := w.traverse(from, func(commit *git.Commit) bool {
+		ids = append(ids, commit.Id())
+		return true
+	}, hidden)
+	if err != nil {
+		return err
+	}
+
+	commits := make([]*git.Commit, 0, len(ids))
+	byId := make(map[string]*git.Commit, len(ids))
+	defer func() {
+		for _, commit := range commits {
+			commit.Free()
+		}
+	}()
+	for _, id := range ids {
+		commit, err := w.repo.LookupCommit(id)
+		if err != nil {
+			return err
+		}
+		commits = append(commits, commit)
+		byId[id.String()] = commit
+	}
+
+	children := make(map[string]int, len(commits))
+	for _, commit := range commits {
+		for _, parent := range w.parents(commit) {
+			if _, ok := byId[parent.String()]; ok {
+				children[parent.String()]++
+			}
+		}
+	}
+
+	queue := &commitQueue{author: w.authorTime}
+	for _, commit := range commits {
+		if children[commit.Id().String()] == 0 {
+			heap.Push(queue, commit)
+		}
+	}
+
+	for queue.Len() > 0 {
+		commit := heap.Pop(queue).(*git.Commit)
+
+		for _, parent := range w.parents(commit) {
+			id := parent.String()
+			if _, ok := byId[id]; !ok {
+				continue
+			}
+			children[id]--
+			if children[id] == 0 {
+				heap.Push(queue, byId[id])
+			}
+		}
+
+		if !fn(commit) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// commitQueue orders commits newest first by committer time, or by author
+// time.
+type commitQueue struct {
+	commits []*git.Commit
+	author  bool
+}
+
+func (q *commitQueue) Len() int { return len(q.commits) }
+func (q *commitQueue) Less(i, j int) bool {
+	if q.author {
+		return q.commits[i].Author().When.After(q.commits[j].Author().When)
+	}
+	return q.commits[i].Committer().When.After(q.commits[j].Committer().When)
+}
+func (q *commitQueue) Swap(i, j int) {
+	q.commits[i], q.commits[j] = q.commits[j], q.commits[i]
+}
+func (q *commitQueue) Push(x interface{}) { q.commits = append(q.commits, x.(*git.Commit)) }
+func (q *commitQueue) Pop() interface{} {
+	commit := q.commits[len(q.commits)-1]
+	q.commits = q.commits[:len(q.commits)-1]
+	return commit
+}
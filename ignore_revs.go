@@ -0,0 +1,77 @@
+package sourcegit
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// loadIgnoreRevs returns the commits never scanned: the ones in ignore-revs
+// and in the ignore-revs-file. Relative files are read from the working
+// directory, or from HEAD for bare repositories and when missing from the
+// working directory. The blame.ignoreRevsFile of
+// the repository is not read: whoever can commit to the repository would
+// decide which of their commits are not scanned.
+func loadIgnoreRevs(repo *git.Repository, opt SourceGitLoadOptions) (map[string]bool, error) {
+	ignored := make(map[string]bool)
+	for _, rev := range opt.IgnoreRevs {
+		ignored[strings.ToLower(strings.TrimSpace(rev))] = true
+	}
+
+	file := opt.IgnoreRevsFile
+	if file == "" {
+		return ignored, nil
+	}
+
+	var data []byte
+	var err error
+	switch {
+	case filepath.IsAbs(file):
+		data, err = ioutil.ReadFile(file)
+	case repo.IsBare():
+		data, err = headFile(repo, filepath.ToSlash(file))
+	default:
+		var p string
+		if p, err = checkoutPath(repo.Workdir(), filepath.ToSlash(file)); err == nil {
+			data, err = ioutil.ReadFile(p)
+		}
+		// Remote sources are cloned without checking out their files.
+		if os.IsNotExist(err) {
+			data, err = headFile(repo, filepath.ToSlash(file))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rev := range parseIgnoreRevs(data) {
+		ignored[rev] = true
+	}
+
+	return ignored, nil
+}
+
+// parseIgnoreRevs parses a .git-blame-ignore-revs file: one commit id per
+// line, with '#' comments.
+func parseIgnoreRevs(data []byte) []string {
+	var revs []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line != "" {
+			revs = append(revs, line)
+		}
+	}
+
+	return revs
+}
@@ -0,0 +1,67 @@
+package sourcegit
+
+import (
+	"bytes"
+)
+
+// incrementalContent tracks the last version emitted for every path, for
+// incremental-content scans.
+type incrementalContent struct {
+	blobs    map[string]string
+	contents map[string][]byte
+}
+
+// unchanged reports whether path was last emitted with the same blob.
+func (ic *incrementalContent) unchanged(path string, blobId string) bool {
+	return ic.blobs != nil && ic.blobs[path] == blobId
+}
+
+// update records content as the last emitted version of path and returns
+// the lines it adds relative to the previous version, along with the blob
+// id of that version. The first version of a path is returned whole.
+func (ic *incrementalContent) update(path string, blobId string, content []byte) ([]byte, string) {
+	if ic.blobs == nil {
+		ic.blobs = make(map[string]string)
+		ic.contents = make(map[string][]byte)
+	}
+
+	prevBlob, seen := ic.blobs[path]
+	prevContent := ic.contents[path]
+	ic.blobs[path] = blobId
+	ic.contents[path] = content
+
+	if !seen {
+		return content, ""
+	}
+
+	return addedLines(prevContent, content), prevBlob
+}
+
+// addedLines returns the lines of next that are not in prev, in order. Lines
+// are compared as a multiset, so moved lines are not reported as added.
+func addedLines(prev []byte, next []byte) []byte {
+	counts := make(map[string]int)
+	for _, line := range bytes.SplitAfter(prev, []byte("\n")) {
+		counts[string(bytes.TrimRight(line, "\r\n"))]++
+	}
+
+	var added bytes.Buffer
+	for _, line := range bytes.SplitAfter(next, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+
+		key := string(bytes.TrimRight(line, "\r\n"))
+		if counts[key] > 0 {
+			counts[key]--
+			continue
+		}
+
+		added.Write(line)
+		if !bytes.HasSuffix(line, []byte("\n")) {
+			added.WriteByte('\n')
+		}
+	}
+
+	return added.Bytes()
+}
@@ -0,0 +1,43 @@
+package sourcegit
+
+import (
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// insteadOf applies the url.<base>.insteadOf rewrites of the global git
+// configuration to source, like the git CLI does: the longest matching
+// prefix is replaced by its base.
+func insteadOf(source string) string {
+	config, err := git.OpenDefault()
+	if err != nil {
+		return source
+	}
+	defer config.Free()
+
+	iter, err := config.NewIteratorGlob(`^url\..*\.insteadof$`)
+	if err != nil {
+		return source
+	}
+	defer iter.Free()
+
+	var base, prefix string
+	for {
+		entry, err := iter.Next()
+		if err != nil {
+			break
+		}
+
+		if strings.HasPrefix(source, entry.Value) && len(entry.Value) > len(prefix) {
+			prefix = entry.Value
+			base = strings.TrimSuffix(strings.TrimPrefix(entry.Name, "url."), ".insteadof")
+		}
+	}
+
+	if prefix == "" {
+		return source
+	}
+
+	return base + strings.TrimPrefix(source, prefix)
+}
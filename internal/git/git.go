@@ -0,0 +1,8 @@
+// Package git is the part of git2go the source uses, for the major of
+// git2go it is built against: v26 by default, or v33 or v34 with the
+// git2go_v33 or git2go_v34 build tag, for the libgit2 release installed.
+//
+// The names, constructors and callback signatures that changed between
+// majors are given a single form here, so that no other package of the
+// module imports git2go.
+package git
@@ -0,0 +1,51 @@
+//go:build git2go_v33 || git2go_v34
+// +build git2go_v33 git2go_v34
+
+package git
+
+// CallbackResult is what transfer progress and certificate check callbacks
+// return: an error in v33 and v34.
+type CallbackResult = error
+
+var (
+	// CallbackOk lets the operation go on.
+	CallbackOk CallbackResult
+	// CallbackAbort aborts the operation.
+	CallbackAbort CallbackResult = &GitError{Message: "operation aborted", Code: ErrorCodeUser}
+	// CallbackRejectCertificate fails the connection to a server whose
+	// certificate is not trusted.
+	CallbackRejectCertificate CallbackResult = &GitError{Message: "certificate not trusted", Code: ErrorCodeCertificate}
+)
+
+// NewCredentialsCallback returns the credentials callback calling f, which
+// gives up on the remote by returning an error.
+func NewCredentialsCallback(f func(url string, username string, allowedTypes CredentialType) (*Credential, error)) CredentialsCallback {
+	return f
+}
+
+// WalkTree walks tree in pre-order, calling f for every entry: f returns 0
+// to go on, 1 to skip the entries of a tree and -1 to stop the walk.
+func WalkTree(tree *Tree, f func(base string, entry *TreeEntry) int) error {
+	return tree.Walk(func(base string, entry *TreeEntry) error {
+		switch ret := f(base, entry); {
+		case ret > 0:
+			return TreeWalkSkip
+		case ret < 0:
+			return &GitError{Message: "tree walk stopped", Code: ErrorCode(ret)}
+		}
+		return nil
+	})
+}
+
+// CloneNoCheckout clones the remote at url in path, without checking out
+// any file.
+func CloneNoCheckout(url string, path string, callbacks RemoteCallbacks) (*Repository, error) {
+	return Clone(url, path, &CloneOptions{
+		FetchOptions: FetchOptions{
+			RemoteCallbacks: callbacks,
+		},
+		CheckoutOptions: CheckoutOptions{
+			Strategy: CheckoutNone,
+		},
+	})
+}
@@ -0,0 +1,179 @@
+//go:build !git2go_v33 && !git2go_v34
+// +build !git2go_v33,!git2go_v34
+
+package git
+
+import (
+	git2go "gopkg.in/libgit2/git2go.v26"
+)
+
+// Types, constants and functions the same in every supported major.
+type (
+	Certificate              = git2go.Certificate
+	CertificateCheckCallback = git2go.CertificateCheckCallback
+	Commit                   = git2go.Commit
+	ConfigLevel              = git2go.ConfigLevel
+	CredentialsCallback      = git2go.CredentialsCallback
+	Diff                     = git2go.Diff
+	DiffDelta                = git2go.DiffDelta
+	DiffForEachHunkCallback  = git2go.DiffForEachHunkCallback
+	DiffForEachLineCallback  = git2go.DiffForEachLineCallback
+	DiffHunk                 = git2go.DiffHunk
+	DiffLine                 = git2go.DiffLine
+	DiffOptions              = git2go.DiffOptions
+	ErrorCode                = git2go.ErrorCode
+	FetchOptions             = git2go.FetchOptions
+	GitError                 = git2go.GitError
+	Index                    = git2go.Index
+	IndexEntry               = git2go.IndexEntry
+	Oid                      = git2go.Oid
+	PushOptions              = git2go.PushOptions
+	Reference                = git2go.Reference
+	RemoteCallbacks          = git2go.RemoteCallbacks
+	Repository               = git2go.Repository
+	RepositoryState          = git2go.RepositoryState
+	Signature                = git2go.Signature
+	Status                   = git2go.Status
+	StatusOptions            = git2go.StatusOptions
+	TransferProgress         = git2go.TransferProgress
+	TransferProgressCallback = git2go.TransferProgressCallback
+	Tree                     = git2go.Tree
+	TreeEntry                = git2go.TreeEntry
+)
+
+const (
+	CertificateX509                     = git2go.CertificateX509
+	ConfigLevelGlobal                   = git2go.ConfigLevelGlobal
+	ConfigLevelSystem                   = git2go.ConfigLevelSystem
+	ConfigLevelXDG                      = git2go.ConfigLevelXDG
+	DeltaDeleted                        = git2go.DeltaDeleted
+	DeltaUntracked                      = git2go.DeltaUntracked
+	DiffDetailLines                     = git2go.DiffDetailLines
+	DiffFlagBinary                      = git2go.DiffFlagBinary
+	DiffIncludeUntracked                = git2go.DiffIncludeUntracked
+	DiffLineAddition                    = git2go.DiffLineAddition
+	DiffLineDeletion                    = git2go.DiffLineDeletion
+	DiffRecurseUntracked                = git2go.DiffRecurseUntracked
+	ObjectBlob                          = git2go.ObjectBlob
+	ObjectCommit                        = git2go.ObjectCommit
+	ObjectTree                          = git2go.ObjectTree
+	ReferenceOid                        = git2go.ReferenceOid
+	RepositoryOpenCrossFs               = git2go.RepositoryOpenCrossFs
+	RepositoryOpenFromEnv               = git2go.RepositoryOpenFromEnv
+	RepositoryOpenNoSearch              = git2go.RepositoryOpenNoSearch
+	RepositoryStateApplyMailbox         = git2go.RepositoryStateApplyMailbox
+	RepositoryStateApplyMailboxOrRebase = git2go.RepositoryStateApplyMailboxOrRebase
+	RepositoryStateBisect               = git2go.RepositoryStateBisect
+	RepositoryStateCherrypick           = git2go.RepositoryStateCherrypick
+	RepositoryStateMerge                = git2go.RepositoryStateMerge
+	RepositoryStateRebase               = git2go.RepositoryStateRebase
+	RepositoryStateRebaseInteractive    = git2go.RepositoryStateRebaseInteractive
+	RepositoryStateRebaseMerge          = git2go.RepositoryStateRebaseMerge
+	RepositoryStateRevert               = git2go.RepositoryStateRevert
+	RevparseMergeBase                   = git2go.RevparseMergeBase
+	RevparseSingle                      = git2go.RevparseSingle
+	SortTime                            = git2go.SortTime
+	SortTopological                     = git2go.SortTopological
+	StatusIndexDeleted                  = git2go.StatusIndexDeleted
+	StatusIndexModified                 = git2go.StatusIndexModified
+	StatusIndexNew                      = git2go.StatusIndexNew
+	StatusIndexRenamed                  = git2go.StatusIndexRenamed
+	StatusIndexTypeChange               = git2go.StatusIndexTypeChange
+	StatusOptRenamesHeadToIndex         = git2go.StatusOptRenamesHeadToIndex
+	StatusShowIndexOnly                 = git2go.StatusShowIndexOnly
+)
+
+var (
+	DefaultDiffOptions     = git2go.DefaultDiffOptions
+	InitRepository         = git2go.InitRepository
+	IsErrorCode            = git2go.IsErrorCode
+	NewOid                 = git2go.NewOid
+	OpenDefault            = git2go.OpenDefault
+	OpenIndex              = git2go.OpenIndex
+	OpenRepository         = git2go.OpenRepository
+	OpenRepositoryExtended = git2go.OpenRepositoryExtended
+	SetSearchPath          = git2go.SetSearchPath
+)
+
+// The v26 names of what later majors renamed.
+type (
+	Credential     = git2go.Cred
+	CredentialType = git2go.CredType
+)
+
+const (
+	CredentialTypeSSHKey            = git2go.CredTypeSshKey
+	CredentialTypeUserpassPlaintext = git2go.CredTypeUserpassPlaintext
+
+	ErrorCodeAuth         = git2go.ErrAuth
+	ErrorCodeCertificate  = git2go.ErrCertificate
+	ErrorCodeIterOver     = git2go.ErrIterOver
+	ErrorCodeNotFound     = git2go.ErrNotFound
+	ErrorCodeUnbornBranch = git2go.ErrUnbornBranch
+	ErrorCodeUser         = git2go.ErrUser
+)
+
+// CallbackResult is what transfer progress and certificate check callbacks
+// return: an error code in v26.
+type CallbackResult = git2go.ErrorCode
+
+const (
+	// CallbackOk lets the operation go on.
+	CallbackOk CallbackResult = git2go.ErrOk
+	// CallbackAbort aborts the operation.
+	CallbackAbort CallbackResult = git2go.ErrUser
+	// CallbackRejectCertificate fails the connection to a server whose
+	// certificate is not trusted.
+	CallbackRejectCertificate CallbackResult = git2go.ErrCertificate
+)
+
+func NewCredentialUserpassPlaintext(username, password string) (*Credential, error) {
+	ret, cred := git2go.NewCredUserpassPlaintext(username, password)
+	if ret < 0 {
+		return nil, &GitError{Message: "creating username and password credential", Code: ErrorCode(ret)}
+	}
+	return &cred, nil
+}
+
+func NewCredentialSSHKey(username, publicKeyPath, privateKeyPath, passphrase string) (*Credential, error) {
+	ret, cred := git2go.NewCredSshKey(username, publicKeyPath, privateKeyPath, passphrase)
+	if ret < 0 {
+		return nil, &GitError{Message: "creating SSH key credential", Code: ErrorCode(ret)}
+	}
+	return &cred, nil
+}
+
+// NewCredentialsCallback returns the credentials callback calling f, which
+// gives up on the remote by returning an error: the code of a *GitError,
+// ErrorCodeAuth for others.
+func NewCredentialsCallback(f func(url string, username string, allowedTypes CredentialType) (*Credential, error)) CredentialsCallback {
+	return func(url string, username string, allowedTypes CredentialType) (ErrorCode, *Credential) {
+		cred, err := f(url, username, allowedTypes)
+		if err != nil {
+			if gitErr, ok := err.(*GitError); ok {
+				return gitErr.Code, nil
+			}
+			return ErrorCodeAuth, nil
+		}
+		return git2go.ErrOk, cred
+	}
+}
+
+// WalkTree walks tree in pre-order, calling f for every entry: f returns 0
+// to go on, 1 to skip the entries of a tree and -1 to stop the walk.
+func WalkTree(tree *Tree, f func(base string, entry *TreeEntry) int) error {
+	return tree.Walk(f)
+}
+
+// CloneNoCheckout clones the remote at url in path, without checking out
+// any file.
+func CloneNoCheckout(url string, path string, callbacks RemoteCallbacks) (*Repository, error) {
+	return git2go.Clone(url, path, &git2go.CloneOptions{
+		FetchOptions: &git2go.FetchOptions{
+			RemoteCallbacks: callbacks,
+		},
+		CheckoutOpts: &git2go.CheckoutOpts{
+			Strategy: git2go.CheckoutNone,
+		},
+	})
+}
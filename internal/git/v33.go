@@ -0,0 +1,125 @@
+//go:build git2go_v33
+// +build git2go_v33
+
+package git
+
+import (
+	git2go "github.com/libgit2/git2go/v33"
+)
+
+// Types, constants and functions the same in every supported major.
+type (
+	Certificate              = git2go.Certificate
+	CertificateCheckCallback = git2go.CertificateCheckCallback
+	Commit                   = git2go.Commit
+	ConfigLevel              = git2go.ConfigLevel
+	CredentialsCallback      = git2go.CredentialsCallback
+	Diff                     = git2go.Diff
+	DiffDelta                = git2go.DiffDelta
+	DiffForEachHunkCallback  = git2go.DiffForEachHunkCallback
+	DiffForEachLineCallback  = git2go.DiffForEachLineCallback
+	DiffHunk                 = git2go.DiffHunk
+	DiffLine                 = git2go.DiffLine
+	DiffOptions              = git2go.DiffOptions
+	ErrorCode                = git2go.ErrorCode
+	FetchOptions             = git2go.FetchOptions
+	GitError                 = git2go.GitError
+	Index                    = git2go.Index
+	IndexEntry               = git2go.IndexEntry
+	Oid                      = git2go.Oid
+	PushOptions              = git2go.PushOptions
+	Reference                = git2go.Reference
+	RemoteCallbacks          = git2go.RemoteCallbacks
+	Repository               = git2go.Repository
+	RepositoryState          = git2go.RepositoryState
+	Signature                = git2go.Signature
+	Status                   = git2go.Status
+	StatusOptions            = git2go.StatusOptions
+	TransferProgress         = git2go.TransferProgress
+	TransferProgressCallback = git2go.TransferProgressCallback
+	Tree                     = git2go.Tree
+	TreeEntry                = git2go.TreeEntry
+)
+
+const (
+	CertificateX509                     = git2go.CertificateX509
+	ConfigLevelGlobal                   = git2go.ConfigLevelGlobal
+	ConfigLevelSystem                   = git2go.ConfigLevelSystem
+	ConfigLevelXDG                      = git2go.ConfigLevelXDG
+	DeltaDeleted                        = git2go.DeltaDeleted
+	DeltaUntracked                      = git2go.DeltaUntracked
+	DiffDetailLines                     = git2go.DiffDetailLines
+	DiffFlagBinary                      = git2go.DiffFlagBinary
+	DiffIncludeUntracked                = git2go.DiffIncludeUntracked
+	DiffLineAddition                    = git2go.DiffLineAddition
+	DiffLineDeletion                    = git2go.DiffLineDeletion
+	DiffRecurseUntracked                = git2go.DiffRecurseUntracked
+	ObjectBlob                          = git2go.ObjectBlob
+	ObjectCommit                        = git2go.ObjectCommit
+	ObjectTree                          = git2go.ObjectTree
+	ReferenceOid                        = git2go.ReferenceOid
+	RepositoryOpenCrossFs               = git2go.RepositoryOpenCrossFs
+	RepositoryOpenFromEnv               = git2go.RepositoryOpenFromEnv
+	RepositoryOpenNoSearch              = git2go.RepositoryOpenNoSearch
+	RepositoryStateApplyMailbox         = git2go.RepositoryStateApplyMailbox
+	RepositoryStateApplyMailboxOrRebase = git2go.RepositoryStateApplyMailboxOrRebase
+	RepositoryStateBisect               = git2go.RepositoryStateBisect
+	RepositoryStateCherrypick           = git2go.RepositoryStateCherrypick
+	RepositoryStateMerge                = git2go.RepositoryStateMerge
+	RepositoryStateRebase               = git2go.RepositoryStateRebase
+	RepositoryStateRebaseInteractive    = git2go.RepositoryStateRebaseInteractive
+	RepositoryStateRebaseMerge          = git2go.RepositoryStateRebaseMerge
+	RepositoryStateRevert               = git2go.RepositoryStateRevert
+	RevparseMergeBase                   = git2go.RevparseMergeBase
+	RevparseSingle                      = git2go.RevparseSingle
+	SortTime                            = git2go.SortTime
+	SortTopological                     = git2go.SortTopological
+	StatusIndexDeleted                  = git2go.StatusIndexDeleted
+	StatusIndexModified                 = git2go.StatusIndexModified
+	StatusIndexNew                      = git2go.StatusIndexNew
+	StatusIndexRenamed                  = git2go.StatusIndexRenamed
+	StatusIndexTypeChange               = git2go.StatusIndexTypeChange
+	StatusOptRenamesHeadToIndex         = git2go.StatusOptRenamesHeadToIndex
+	StatusShowIndexOnly                 = git2go.StatusShowIndexOnly
+)
+
+var (
+	DefaultDiffOptions     = git2go.DefaultDiffOptions
+	InitRepository         = git2go.InitRepository
+	IsErrorCode            = git2go.IsErrorCode
+	NewOid                 = git2go.NewOid
+	OpenDefault            = git2go.OpenDefault
+	OpenIndex              = git2go.OpenIndex
+	OpenRepository         = git2go.OpenRepository
+	OpenRepositoryExtended = git2go.OpenRepositoryExtended
+	SetSearchPath          = git2go.SetSearchPath
+)
+
+// Types, constants and functions renamed or added since v26.
+type (
+	CheckoutOptions = git2go.CheckoutOptions
+	CloneOptions    = git2go.CloneOptions
+	Credential      = git2go.Credential
+	CredentialType  = git2go.CredentialType
+)
+
+const (
+	CheckoutNone = git2go.CheckoutNone
+
+	CredentialTypeSSHKey            = git2go.CredentialTypeSSHKey
+	CredentialTypeUserpassPlaintext = git2go.CredentialTypeUserpassPlaintext
+
+	ErrorCodeAuth         = git2go.ErrorCodeAuth
+	ErrorCodeCertificate  = git2go.ErrorCodeCertificate
+	ErrorCodeIterOver     = git2go.ErrorCodeIterOver
+	ErrorCodeNotFound     = git2go.ErrorCodeNotFound
+	ErrorCodeUnbornBranch = git2go.ErrorCodeUnbornBranch
+	ErrorCodeUser         = git2go.ErrorCodeUser
+)
+
+var (
+	Clone                          = git2go.Clone
+	NewCredentialSSHKey            = git2go.NewCredentialSSHKey
+	NewCredentialUserpassPlaintext = git2go.NewCredentialUserpassPlaintext
+	TreeWalkSkip                   = git2go.TreeWalkSkip
+)
@@ -0,0 +1,87 @@
+package sourcegit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/apuigsech/seekret/models"
+)
+
+// knownSecrets holds the SHA-256 fingerprints of leaked secret values, so
+// history can be searched for them without the values themselves.
+type knownSecrets map[[sha256.Size]byte]string
+
+// newKnownSecrets parses known-secrets. A malformed fingerprint fails the
+// load: skipping it would search for nothing while dropping every object.
+func newKnownSecrets(fingerprints []string) (knownSecrets, error) {
+	if len(fingerprints) == 0 {
+		return nil, nil
+	}
+
+	k := make(knownSecrets, len(fingerprints))
+	for _, f := range fingerprints {
+		sum, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(f)), "sha256:"))
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("known-secrets: invalid SHA-256 fingerprint %q", f)
+		}
+
+		var key [sha256.Size]byte
+		copy(key[:], sum)
+		k[key] = hex.EncodeToString(sum)
+	}
+
+	return k, nil
+}
+
+// isTokenSeparator delimits the candidate values of a content: whitespace,
+// quotes and the punctuation of common configuration and source formats.
+func isTokenSeparator(r rune) bool {
+	switch r {
+	case ' ', '\t', '\r', '\n', '"', '\'', '`', ',', ';', '(', ')', '[', ']', '{', '}', '<', '>':
+		return true
+	}
+
+	return false
+}
+
+// find returns the fingerprints of the known secrets in content. Candidate
+// values are the tokens of content, and what follows the first ':' or '='
+// of each token ("password=value", "key:value").
+func (k knownSecrets) find(content []byte) []string {
+	var found []string
+	seen := make(map[string]bool)
+
+	check := func(token []byte) {
+		if len(token) == 0 {
+			return
+		}
+		if f, ok := k[sha256.Sum256(token)]; ok && !seen[f] {
+			seen[f] = true
+			found = append(found, f)
+		}
+	}
+
+	for _, token := range bytes.FieldsFunc(content, isTokenSeparator) {
+		check(token)
+		if i := bytes.IndexAny(token, ":="); i >= 0 {
+			check(bytes.TrimLeft(token[i+1:], ":="))
+		}
+	}
+
+	return found
+}
+
+// knownSecret reports whether o contains a known secret, recording which in
+// the "known-secret" metadata.
+func (c *objectCollector) knownSecret(o *models.Object) bool {
+	found := c.knownSecrets.find(o.Content)
+	if len(found) == 0 {
+		return false
+	}
+	o.SetMetadata("known-secret", strings.Join(found, ","), models.MetadataAttributes{})
+
+	return true
+}
@@ -0,0 +1,142 @@
+package sourcegit
+
+import (
+	"fmt"
+	"github.com/apuigsech/seekret"
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+	"strings"
+	"sync"
+)
+
+// ContentLoader returns the content of an object on demand.
+type ContentLoader func() ([]byte, error)
+
+// LazyObjects is the result of LoadObjectsLazy: objects whose file contents
+// are only read from the repository when asked for. The repository stays
+// open until Close.
+type LazyObjects struct {
+	Objects []models.Object
+	Report  *LoadReport
+
+	mu     sync.Mutex
+	source *SourceGit
+	repo   *git.Repository
+	// The SHA-256 repository read with the git CLI, instead of repo.
+	sha256Path string
+}
+
+// LoadObjectsLazy loads the objects of source like LoadObjectsWithReport, but
+// without the content of files, which is read through Content or Loader.
+// Commit messages and other small objects keep their content. Archives and
+// sources covering several repositories (gist listings, include-wiki) are
+// not supported, nor are incremental-content and hash-only.
+func (s *SourceGit) LoadObjectsLazy(source string, opta seekret.LoadOptions) (*LazyObjects, error) {
+	if _, ok := gistUser(source); ok {
+		return nil, fmt.Errorf("%s: lazy loading of gist listings is not supported", source)
+	}
+	if isArchiveSource(source) {
+		return nil, fmt.Errorf("%s: lazy loading of archives is not supported", source)
+	}
+	if hashOnly, _ := opta["hash-only"].(bool); hashOnly {
+		return nil, fmt.Errorf("%s: lazy loading with hash-only is not supported", source)
+	}
+	if incrementalContent, _ := opta["incremental-content"].(bool); incrementalContent {
+		return nil, fmt.Errorf("%s: lazy loading with incremental-content is not supported", source)
+	}
+
+	lazyOpts := make(seekret.LoadOptions, len(opta)+2)
+	for k, v := range opta {
+		lazyOpts[k] = v
+	}
+	lazyOpts["metadata-only"] = true
+	lazyOpts["include-wiki"] = false
+
+	objectList, report, repo, err := s.load(source, lazyOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	lazy := &LazyObjects{
+		Objects: objectList,
+		Report:  report,
+		source:  s,
+		repo:    repo,
+	}
+	if report.ObjectFormat == objectFormatSha256 {
+		lazy.sha256Path = localSource(source)
+	}
+
+	return lazy, nil
+}
+
+// Content returns the content of o: its own content when it has one, or the
+// content of its blob read from the repository.
+func (l *LazyObjects) Content(o *models.Object) ([]byte, error) {
+	if o.Content != nil {
+		return o.Content, nil
+	}
+
+	uniqId, err := o.GetMetadata("uniq-id")
+	if err != nil {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var content []byte
+	if strings.HasPrefix(uniqId, objectFormatSha256+":") {
+		if l.sha256Path == "" {
+			return nil, fmt.Errorf("%s: content not available, objects are closed", o.Name)
+		}
+		if content, err = gitCommand(l.sha256Path, "cat-file", "blob", uniqIdOid(uniqId)).Output(); err != nil {
+			return nil, fmt.Errorf("%s: reading blob %s: %v", o.Name, uniqIdOid(uniqId), err)
+		}
+	} else {
+		oid, err := git.NewOid(uniqId)
+		if err != nil {
+			return nil, err
+		}
+
+		if l.repo == nil || !l.source.holdsRepo(l.repo) {
+			return nil, fmt.Errorf("%s: content not available, objects are closed", o.Name)
+		}
+
+		blob, err := l.repo.LookupBlob(oid)
+		if err != nil {
+			return nil, err
+		}
+		content = blob.Contents()
+		blob.Free()
+	}
+
+	if redactor := l.source.contentRedactor(); redactor != nil {
+		return redactor(content), nil
+	}
+
+	return content, nil
+}
+
+// Loader returns a loader of the content of o, for pipelines that pass
+// loaders around instead of contents.
+func (l *LazyObjects) Loader(o *models.Object) ContentLoader {
+	return func() ([]byte, error) {
+		return l.Content(o)
+	}
+}
+
+// Close releases the repository, removing it when it is a temporary clone.
+// Contents can no longer be loaded after.
+func (l *LazyObjects) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.repo != nil {
+		l.source.releaseRepo(l.repo)
+		l.repo = nil
+	}
+	l.sha256Path = ""
+
+	return nil
+}
@@ -1,14 +1,16 @@
 package sourcegit
 
 import (
+	"errors"
 	"fmt"
-	"github.com/emptyinterface/sshconfig"
-	"gopkg.in/libgit2/git2go.v26"
-	"io/ioutil"
-	"net/url"
 	"os"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 	"github.com/apuigsech/seekret"
+	"github.com/apuigsech/seekret-source-git/internal/git"
 	"github.com/apuigsech/seekret/models"
 )
 
@@ -21,222 +23,1797 @@ const (
 	Type = "seekret-source-git"
 )
 
-type SourceGit struct{}
+// SourceGit is safe for concurrent use: every LoadObjects call opens its own
+// repository handle and keeps its state to itself, so many repositories can
+// be scanned in parallel from one process (libgit2 is initialized with thread
+// support by git2go). Concurrent scans must not share a checkpoint-file, a
+// since-last-scan file or a spool-dir used with resume. The only state shared
+// by every SourceGit of the process is the root of their temporary clones and
+// IsolateProcessConfig, which must be called before any load starts.
+type SourceGit struct{
+	mu       sync.RWMutex
+	filter   ObjectFilter
+	redactor Redactor
+	metrics  Metrics
+	dedup    DedupCache
+	limiter  *cloneLimiter
+
+	// Repositories handed out still open (see LazyObjects), and the
+	// temporary directory of each.
+	repos map[*git.Repository]string
+}
+
+// ObjectFilter is applied to every object before it is added to the result of
+// LoadObjects. It may modify the object in place (redact, truncate, enrich
+// metadata); returning false drops the object.
+type ObjectFilter func(o *models.Object) bool
+
+// SetObjectFilter installs filter on the source, replacing any previous one.
+// A nil filter keeps every object unchanged. The filter is shared by every
+// scan running on the source, so it must be safe for concurrent use.
+func (s *SourceGit) SetObjectFilter(filter ObjectFilter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.filter = filter
+}
+
+func (s *SourceGit) objectFilter() ObjectFilter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.filter
+}
+
+
+type SourceGitLoadOptions struct {
+	// profile: Named preset of options ("quick", "ci", "deep-forensic" or
+	// "pre-commit"). Options set explicitly override the preset.
+	Profile string
+
+	// commit-files: Include commited file content as object.
+	CommitFiles bool
+	// commit-messages: Include commit contect as object.
+	CommitMessages bool
+	// staged-files: Include stateg dile contect as object.
+	StagedFiles bool
+	// worktree-changes: Include the lines staged ("staged-change"), the
+	// lines changed but not staged ("unstaged-change") and the untracked
+	// files ("untracked-file") of the working directory, for pre-commit
+	// hooks applying a different policy to each.
+	WorktreeChanges bool
+	// deleted-files: Include files deleted in history, with the content of
+	// their last existing revision.
+	DeletedFiles bool
+	// split-commit-messages: Include commit subject and body as separate
+	// objects, with "message-part" metadata.
+	SplitCommitMessages bool
+	// message-min-length: Skip commit messages shorter than this.
+	MessageMinLength int
+	// message-noise: Skip commit messages of bots (dependabot, renovate,
+	// ...) and merge messages left as git wrote them.
+	MessageNoise bool
+	// message-noise-patterns: Skip commit messages matching these regular
+	// expressions.
+	MessageNoisePatterns []string
+	// commit-metadata: Include a JSON description of every commit (author,
+	// committer, message, parents, changed paths) as object.
+	CommitMetadata bool
+	// mailmap: Canonicalize author and committer identities with the
+	// .mailmap of the repository (default true).
+	Mailmap bool
+
+	// commit-count: Ammount of commits to analise.
+	CommitCount int
+	// head-only: Only scan the commit at HEAD. Remotes are then cloned with
+	// depth 1 instead of downloading their whole history.
+	HeadOnly bool
+
+	// all-branches: Walk every local and remote-tracking branch instead of
+	// HEAD only.
+	AllBranches bool
+	// refs: Walk the refs matching these globs (e.g. "refs/tags/v*") instead
+	// of HEAD only. Globs on refs/heads also match remote-tracking branches.
+	Refs []string
+	// default-branch-only: Only walk the default branch of the repository
+	// (see LoadReport.DefaultBranch), whatever HEAD points to.
+	DefaultBranchOnly bool
+	// exclude-refs: Skip the refs matching these globs (e.g.
+	// "refs/heads/dependabot/*") when walking several refs.
+	ExcludeRefs []string
+	// exclude-commits: Never walk the commits reachable from these commit
+	// ids, whatever the refs walked.
+	ExcludeCommits []string
+
+	// pathspec: Only walk commits touching these paths, and only include
+	// files below them.
+	Pathspec []string
+
+	// path-exclude: Skip files matching these paths, leading directories or
+	// globs. Container build and CI configuration files (Dockerfiles,
+	// docker-compose files, .github/workflows, .gitlab-ci.yml, Jenkinsfile,
+	// ...) are always included, with "build-config" metadata, and so are
+	// dotfiles and dotfile directories (.github, .circleci, .vscode, ...)
+	// outside of the excluded directories.
+	PathExclude []string
+	// path-exclude-dotfiles: Let path-exclude exclude dotfiles too.
+	PathExcludeDotfiles bool
+	// dotfiles-only: Only include dotfiles and the files of dotfile
+	// directories, for a quick sweep of hidden configuration.
+	DotfilesOnly bool
+
+	// skip-blobs: Ids of blobs whose content is never loaded, e.g. known
+	// clean large files.
+	SkipBlobs []string
+	// include-blobs: Ids of blobs always returned wherever they are reached,
+	// regardless of path-exclude, skip-blobs and the DedupCache, with
+	// "included-blob" metadata, e.g. to track where known compromised
+	// content spread.
+	IncludeBlobs []string
+	// known-secrets: SHA-256 fingerprints ("sha256:<hex>" or hex) of leaked
+	// secret values. Only objects containing one of them are returned, with
+	// "known-secret" metadata, to find where else a secret spread. Values
+	// are matched as whole tokens, or after the ':' or '=' of a token. It
+	// needs contents, so it finds nothing with metadata-only.
+	KnownSecrets []string
+
+	// skip-generated: Skip generated files: lock files (package-lock.json,
+	// go.sum, ...), generated code (*.pb.go, files with a "Code generated"
+	// or "@generated" comment), minified JavaScript and CSS, and source
+	// maps. Without it, they are returned with "generated" metadata.
+	SkipGenerated bool
+	// generated-patterns: More paths, leading directories or globs of
+	// generated files.
+	GeneratedPatterns []string
+
+	// max-path-depth: Only include files with at most this many path
+	// components (1 is the top of the tree only). Deeper directories are not
+	// even walked.
+	MaxPathDepth int
+
+	// commit-diffs: Include the lines added by every commit to each file,
+	// relative to its first parent, as "commit-diff" objects.
+	CommitDiffs bool
+	// diff-filetypes: Only include commit diffs of files of these types,
+	// given as filetype names ("yaml", "dotenv") or extensions ("yml",
+	// "env").
+	DiffFiletypes []string
+
+	// no-search-parents: Only open a local source if it is the top of a
+	// repository, never a repository found in a parent directory.
+	NoSearchParents bool
+	// ceiling-directories: Directories where the search for the repository
+	// of a local source stops, like GIT_CEILING_DIRECTORIES.
+	CeilingDirectories []string
+	// ignore-git-env: Open local sources by their path even when GIT_DIR,
+	// GIT_WORK_TREE or GIT_INDEX_FILE are set. By default, these are
+	// honored like git does (e.g. from server-side hooks), and the path,
+	// no-search-parents and ceiling-directories are ignored when GIT_DIR is
+	// set.
+	IgnoreGitEnv bool
+
+	// include-wiki: Also scan the wiki repository of GitHub and GitLab
+	// projects (<repo>.wiki.git), with "wiki" metadata.
+	IncludeWiki bool
+
+	// revision-range: Only walk the commits of a revision range ("A..B"),
+	// or the history of a single revision.
+	RevisionRange string
+	// push-update: Only walk the commits a push introduces, given as
+	// "<old> <new> <ref>" like the arguments of an update hook (see
+	// EvaluatePush).
+	PushUpdate string
+
+	// forks: Instead of the repository history, walk the commits that the
+	// forks of the GitHub repository have and upstream has not, with "fork"
+	// and "branch" metadata.
+	Forks bool
+
+	// pull-requests: Also include the description of every pull request of
+	// the GitHub repository and the review comments on them.
+	PullRequests bool
+	// releases: Also include the notes of every release of the GitHub
+	// repository.
+	Releases bool
+	// issues: Also include every issue of the GitHub repository and the
+	// comments on issues and pull requests.
+	Issues bool
+
+	// github-token: Token for the GitHub API, used to enumerate gists and
+	// other content. Defaults to $GITHUB_TOKEN.
+	GithubToken string
+	// github-api-url: Base URL of the GitHub API, for GitHub Enterprise.
+	GithubApiUrl string
+
+	// strict-redirects: Fail the clone of HTTP(S) remotes redirecting
+	// anywhere with ErrUnexpectedRedirect, before credentials are offered to
+	// the target. Such remotes are cloned with the git CLI.
+	StrictRedirects bool
+
+	// ca-bundle: PEM file of more certificate authorities to trust for
+	// HTTPS remotes, e.g. an internal CA.
+	CaBundle string
+	// client-cert, client-key: PEM client certificate and key for HTTPS
+	// remotes requiring mutual TLS. Such remotes are cloned with the git
+	// CLI.
+	ClientCert string
+	ClientKey string
+
+	// fail-on-corruption: Abort when an object cannot be read instead of
+	// recording it in the load report and going on.
+	FailOnCorruption bool
+
+	// partial-clone: What to do with blobs a partial clone has not fetched:
+	// "skip" records them in the load report, "fetch" downloads them in
+	// batches of partial-clone-batch objects. Partial clones made by "git
+	// clone --filter" are opened through a temporary view libgit2 can read
+	// (see openPartialClone), and need the git CLI.
+	PartialClone string
+	PartialCloneBatch int
+
+	// clone-blob-limit: Have the server leave blobs larger than this (in
+	// bytes, or a size such as "1m") out of remote clones. They are then
+	// handled as set by partial-clone.
+	CloneBlobLimit int64
+	// clone-tree-filter: Have the server also leave out the trees deeper
+	// than max-path-depth. Only for scans walking the commit trees: options
+	// diffing commits cannot be combined with clone filters.
+	CloneTreeFilter bool
+
+	// max-bandwidth: Maximum download speed of remote clones and fetches, in
+	// bytes (or a size such as "512k") per second. Clones done with the git
+	// CLI are only throttled over HTTPS, through a local proxy chained to
+	// the configured one (http.proxy, HTTPS_PROXY), which must be an HTTP
+	// proxy.
+	MaxBandwidth int64
+
+	// sandbox-clone: Clone remotes with the git CLI in a restricted
+	// subprocess instead of with libgit2 in-process: no inherited
+	// environment, global configuration nor credential helpers (only
+	// credentials in the URL work). sandbox-memory-limit (bytes, or a size
+	// such as "2g") and sandbox-cpu-limit (seconds) bound its resources on
+	// Unix systems.
+	SandboxClone bool
+	SandboxMemoryLimit int64
+	SandboxCpuLimit int
+
+	// fetch-refs: Fetch these refspecs from fetch-remote (default "origin")
+	// and only scan the commits the fetch brought in.
+	FetchRefs []string
+	FetchRemote string
+
+	// no-replace-objects: Ignore refs/replace/* and walk the original
+	// commits, like GIT_NO_REPLACE_OBJECTS.
+	NoReplaceObjects bool
+	// ignore-grafts: Walk past the shallow boundary as far as the object
+	// database has the true parents.
+	IgnoreGrafts bool
+
+	// incremental-content: For every new version of a path, only include
+	// the lines added since the previously included version.
+	IncrementalContent bool
+
+	// codeowners: Add to every file content the owners given by the
+	// CODEOWNERS file at HEAD ("owners", space separated).
+	Codeowners bool
+
+	// metadata-only: Emit file objects without content, with their size in
+	// the "size" metadata, for inventories and pipelines reading contents
+	// themselves. Blobs of commits are not even read.
+	MetadataOnly bool
+
+	// hash-only: Emit hashes instead of contents: their SHA-256 in the
+	// "content-sha256" metadata, and as content the hash of every shingle of
+	// hash-shingle-size lines (default 1), one per line, for known-secret
+	// matching downstream without exporting the code. hash-key makes them
+	// HMAC-SHA256 with this key.
+	HashOnly bool
+	HashShingleSize int
+	HashKey string
+
+	// type-names: Names to emit instead of the object types of this source
+	// ("file-content", "commit-message", ...), e.g. {"file-content":
+	// "git-file"}. The object filter still sees the original names.
+	TypeNames map[string]string
+	// type-prefix: Namespace prefix added to every object type name, e.g.
+	// "git:" for "git:file-content".
+	TypePrefix string
+
+	// blob-lifetime: Add to every file content the commits where its blob
+	// was first and last seen ("first-seen", "last-seen"). Implies at-head.
+	BlobLifetime bool
+	// at-head: Flag every file content with whether its blob is still
+	// anywhere at HEAD ("at-head") and still at the same path
+	// ("path-at-head").
+	AtHead bool
+
+	// memory-limit: Maximum amount of object content (in bytes, or a size
+	// like "512MB") to hold in memory. memory-limit-action decides what
+	// happens past it: "abort" (default) or "spool" to disk, which
+	// LoadObjects does not support.
+	MemoryLimit int64
+	MemoryLimitAction string
+
+	// spool: Write every object content to a temporary directory (inside
+	// spool-dir if set) instead of keeping it in memory. Use
+	// OpenObjectContent to read the content of returned objects.
+	Spool bool
+	SpoolDir string
+
+	// checkpoint-file: Periodically record the processed commits in this
+	// file (every checkpoint-interval commits). With resume, the commits
+	// recorded by a previous, interrupted load are skipped.
+	CheckpointFile string
+	CheckpointInterval int
+	Resume bool
+
+	// max-duration: Stop scanning once the load has been running this long
+	// (time.Duration, a string such as "30m", or seconds) and return what
+	// was collected, with where it stopped in the load report. With
+	// checkpoint-file, the checkpoint is kept so that a follow-up load with
+	// resume goes on from there. The clone counts too: one not done by
+	// then is aborted and the load fails.
+	MaxDuration time.Duration
+
+	// since-last-scan: File recording the ref tips of the last load of the
+	// source. Only the commits reachable from the current tips and not from
+	// the recorded ones are walked (old tip..new tip), whatever their dates.
+	// The file is updated once the load completes. Options restricting the
+	// walk (commit-count, since, sampling...) cannot be combined with it.
+	SinceLastScan string
+
+	// evidence-file: Once the load completes, write to this file which
+	// commits and ref tips were scanned, with which options, and a digest of
+	// the objects returned. The file carries its own SHA-256 and an
+	// HMAC-SHA256 with evidence-key, which is required: anyone could
+	// recompute a bare digest (see VerifyEvidence). Repositories scanned on
+	// behalf of the source (gists, wiki) are not covered.
+	EvidenceFile string
+	EvidenceKey string
+
+	// stream-file: Write every object to this file as soon as it is
+	// emitted, instead of returning it, so huge scans never hold their
+	// objects in memory (see StreamRecord). stream-format is "jsonl" (the
+	// default) or "gob"; stream-content is "full" (the default, base64 in
+	// JSON Lines), "hash" for its SHA-256 only, or "none". blob-lifetime
+	// and at-head need every object and cannot be combined with it, and
+	// streamed objects have no "tz-anomaly".
+	StreamFile string
+	StreamFormat string
+	StreamContent string
+
+	// sample-every-n: Only scan every Nth commit of the walk.
+	SampleEveryN int
+	// sample-period: Only scan one commit per "day", "week" or "month".
+	SamplePeriod string
+
+	// dedup-patch-id: Skip commits introducing the same change (same patch
+	// id) as an already scanned one, e.g. cherry-picks across branches.
+	DedupPatchId bool
+
+	// untrusted-authors: Trusted email domains. Only scan commits whose
+	// author or committer has an email outside all of them, e.g. external
+	// contributions.
+	UntrustedAuthors []string
+
+	// ignore-revs: Commits never scanned, e.g. mass reformatting or
+	// vendored imports. Their history is still walked.
+	IgnoreRevs []string
+	// ignore-revs-file: File listing more commits to ignore, in the format
+	// of .git-blame-ignore-revs. Relative paths are inside the repository.
+	// The blame.ignoreRevsFile of the repository is never used.
+	IgnoreRevsFile string
+	// scan-notes-ref: Skip commits with a note in this notes ref, i.e.
+	// recorded as scanned by WriteScanNotes. It is fetched from origin when
+	// missing, e.g. in clones of remotes.
+	ScanNotesRef string
+
+	// oldest-first: Return history from the oldest commit to the newest
+	// instead of newest first. With commit-count, the newest commits are
+	// still the ones selected.
+	OldestFirst bool
+	// sort: Order of the history walk: "time" (committer time, the
+	// default), "topo" (children always before their parents) or "reverse"
+	// (same as oldest-first).
+	Sort string
+	// ref-commit-budget: Scan at most this many commits of each ref walked
+	// (all-branches, refs), not counting those scanned for a previous ref.
+	RefCommitBudget int
+	// round-robin-refs: Scan one commit of each ref in turn instead of ref
+	// after ref.
+	RoundRobinRefs bool
+
+	// since, until: Only scan commits dated within this range (time.Time,
+	// or RFC 3339 or "2006-01-02" strings). Both ends are included, and an
+	// until date without a time includes that whole day. The history
+	// beyond is still walked, as dates are not ordered across clock skew.
+	Since time.Time
+	Until time.Time
+	// date-field: Date the date filters, sample-period and the "time" sort
+	// go by: "committer" (the default) or "author", which differ for
+	// rebased commits.
+	DateField string
+
+	// When max-duration is reached, set by the load for the clone.
+	deadline time.Time
+}
+
+func prepareGitLoadOptions(o seekret.LoadOptions) SourceGitLoadOptions {
+	opt := SourceGitLoadOptions{
+		CommitFiles: false,
+		CommitMessages: false,
+		StagedFiles: false,
+		DeletedFiles: false,
+		CommitMetadata: false,
+
+		Mailmap: true,
+
+		CommitCount: 0,
+
+		AllBranches: false,
+
+		PartialClone: "skip",
+		PartialCloneBatch: 100,
+
+		FetchRemote: "origin",
+
+		MemoryLimitAction: "abort",
+		Sort: "time",
+	}
+
+	o = ApplyProfile(o)
+
+	if profile, ok := o["profile"].(string); ok {
+		opt.Profile = profile
+	}
+
+	if commit, ok := o["commit-files"].(bool); ok {
+		opt.CommitFiles = commit
+	}
+
+	if commitMessages, ok := o["commit-messages"].(bool); ok {
+		opt.CommitMessages = commitMessages
+	}
+
+	if stagedFiles, ok := o["staged-files"].(bool); ok {
+		opt.StagedFiles = stagedFiles
+	}
+
+	if worktreeChanges, ok := o["worktree-changes"].(bool); ok {
+		opt.WorktreeChanges = worktreeChanges
+	}
+
+	if deletedFiles, ok := o["deleted-files"].(bool); ok {
+		opt.DeletedFiles = deletedFiles
+	}
+
+	if splitCommitMessages, ok := o["split-commit-messages"].(bool); ok {
+		opt.SplitCommitMessages = splitCommitMessages
+	}
+
+	if messageMinLength, ok := o["message-min-length"].(int); ok {
+		opt.MessageMinLength = messageMinLength
+	}
+
+	if messageNoise, ok := o["message-noise"].(bool); ok {
+		opt.MessageNoise = messageNoise
+	}
+
+	if messageNoisePatterns, ok := stringListOption(o["message-noise-patterns"]); ok {
+		opt.MessageNoisePatterns = messageNoisePatterns
+	}
+
+	if commitMetadata, ok := o["commit-metadata"].(bool); ok {
+		opt.CommitMetadata = commitMetadata
+	}
+
+	if mailmap, ok := o["mailmap"].(bool); ok {
+		opt.Mailmap = mailmap
+	}
+
+	if commitCount, ok := o["commit-count"].(int); ok {
+		opt.CommitCount = commitCount
+	}
+
+	if headOnly, ok := o["head-only"].(bool); ok {
+		opt.HeadOnly = headOnly
+		if headOnly {
+			opt.CommitCount = 1
+		}
+	}
+
+	if allBranches, ok := o["all-branches"].(bool); ok {
+		opt.AllBranches = allBranches
+	}
+
+	if refs, ok := stringListOption(o["refs"]); ok {
+		opt.Refs = refs
+	}
+
+	if defaultBranchOnly, ok := o["default-branch-only"].(bool); ok {
+		opt.DefaultBranchOnly = defaultBranchOnly
+	}
+
+	if excludeRefs, ok := stringListOption(o["exclude-refs"]); ok {
+		opt.ExcludeRefs = excludeRefs
+	}
+
+	if excludeCommits, ok := stringListOption(o["exclude-commits"]); ok {
+		opt.ExcludeCommits = excludeCommits
+	}
+
+	if pathspec, ok := stringListOption(o["pathspec"]); ok {
+		opt.Pathspec = pathspec
+	}
+
+	if pathExclude, ok := stringListOption(o["path-exclude"]); ok {
+		opt.PathExclude = pathExclude
+	}
+
+	if pathExcludeDotfiles, ok := o["path-exclude-dotfiles"].(bool); ok {
+		opt.PathExcludeDotfiles = pathExcludeDotfiles
+	}
+
+	if dotfilesOnly, ok := o["dotfiles-only"].(bool); ok {
+		opt.DotfilesOnly = dotfilesOnly
+	}
+
+	if skipBlobs, ok := stringListOption(o["skip-blobs"]); ok {
+		opt.SkipBlobs = skipBlobs
+	}
+
+	if includeBlobs, ok := stringListOption(o["include-blobs"]); ok {
+		opt.IncludeBlobs = includeBlobs
+	}
+
+	if skipGenerated, ok := o["skip-generated"].(bool); ok {
+		opt.SkipGenerated = skipGenerated
+	}
+
+	if generatedPatterns, ok := stringListOption(o["generated-patterns"]); ok {
+		opt.GeneratedPatterns = generatedPatterns
+	}
+
+	if maxPathDepth, ok := o["max-path-depth"].(int); ok {
+		opt.MaxPathDepth = maxPathDepth
+	}
+
+	if knownSecrets, ok := stringListOption(o["known-secrets"]); ok {
+		opt.KnownSecrets = knownSecrets
+	}
+
+	if commitDiffs, ok := o["commit-diffs"].(bool); ok {
+		opt.CommitDiffs = commitDiffs
+	}
+
+	if diffFiletypes, ok := stringListOption(o["diff-filetypes"]); ok {
+		opt.DiffFiletypes = diffFiletypes
+	}
+
+	if noSearchParents, ok := o["no-search-parents"].(bool); ok {
+		opt.NoSearchParents = noSearchParents
+	}
+
+	if ceilingDirectories, ok := stringListOption(o["ceiling-directories"]); ok {
+		opt.CeilingDirectories = ceilingDirectories
+	}
+
+	if ignoreGitEnv, ok := o["ignore-git-env"].(bool); ok {
+		opt.IgnoreGitEnv = ignoreGitEnv
+	}
+
+	if includeWiki, ok := o["include-wiki"].(bool); ok {
+		opt.IncludeWiki = includeWiki
+	}
+
+	if revisionRange, ok := o["revision-range"].(string); ok {
+		opt.RevisionRange = revisionRange
+	}
+
+	if pushUpdate, ok := o["push-update"].(string); ok {
+		opt.PushUpdate = pushUpdate
+	}
+
+	if forks, ok := o["forks"].(bool); ok {
+		opt.Forks = forks
+	}
+
+	if pullRequests, ok := o["pull-requests"].(bool); ok {
+		opt.PullRequests = pullRequests
+	}
+
+	if releases, ok := o["releases"].(bool); ok {
+		opt.Releases = releases
+	}
+
+	if issues, ok := o["issues"].(bool); ok {
+		opt.Issues = issues
+	}
+
+	if githubToken, ok := o["github-token"].(string); ok {
+		opt.GithubToken = githubToken
+	}
+
+	if githubApiUrl, ok := o["github-api-url"].(string); ok {
+		opt.GithubApiUrl = githubApiUrl
+	}
+
+	if strictRedirects, ok := o["strict-redirects"].(bool); ok {
+		opt.StrictRedirects = strictRedirects
+	}
+
+	if caBundle, ok := o["ca-bundle"].(string); ok {
+		opt.CaBundle = caBundle
+	}
+
+	if clientCert, ok := o["client-cert"].(string); ok {
+		opt.ClientCert = clientCert
+	}
+
+	if clientKey, ok := o["client-key"].(string); ok {
+		opt.ClientKey = clientKey
+	}
+
+	if failOnCorruption, ok := o["fail-on-corruption"].(bool); ok {
+		opt.FailOnCorruption = failOnCorruption
+	}
+
+	if partialClone, ok := o["partial-clone"].(string); ok {
+		opt.PartialClone = partialClone
+	}
+
+	if partialCloneBatch, ok := o["partial-clone-batch"].(int); ok {
+		opt.PartialCloneBatch = partialCloneBatch
+	}
+
+	if cloneBlobLimit, ok := byteSizeOption(o["clone-blob-limit"]); ok {
+		opt.CloneBlobLimit = cloneBlobLimit
+	}
+
+	if cloneTreeFilter, ok := o["clone-tree-filter"].(bool); ok {
+		opt.CloneTreeFilter = cloneTreeFilter
+	}
+
+	if maxBandwidth, ok := byteSizeOption(o["max-bandwidth"]); ok {
+		opt.MaxBandwidth = maxBandwidth
+	}
+
+	if sandboxClone, ok := o["sandbox-clone"].(bool); ok {
+		opt.SandboxClone = sandboxClone
+	}
+
+	if sandboxMemoryLimit, ok := byteSizeOption(o["sandbox-memory-limit"]); ok {
+		opt.SandboxMemoryLimit = sandboxMemoryLimit
+	}
+
+	if sandboxCpuLimit, ok := o["sandbox-cpu-limit"].(int); ok {
+		opt.SandboxCpuLimit = sandboxCpuLimit
+	}
+
+	if fetchRefs, ok := stringListOption(o["fetch-refs"]); ok {
+		opt.FetchRefs = fetchRefs
+	}
+
+	if fetchRemote, ok := o["fetch-remote"].(string); ok {
+		opt.FetchRemote = fetchRemote
+	}
+
+	if noReplaceObjects, ok := o["no-replace-objects"].(bool); ok {
+		opt.NoReplaceObjects = noReplaceObjects
+	}
+
+	if ignoreGrafts, ok := o["ignore-grafts"].(bool); ok {
+		opt.IgnoreGrafts = ignoreGrafts
+	}
+
+	if incrementalContent, ok := o["incremental-content"].(bool); ok {
+		opt.IncrementalContent = incrementalContent
+	}
+
+	if codeowners, ok := o["codeowners"].(bool); ok {
+		opt.Codeowners = codeowners
+	}
+
+	if metadataOnly, ok := o["metadata-only"].(bool); ok {
+		opt.MetadataOnly = metadataOnly
+	}
+
+	if hashOnly, ok := o["hash-only"].(bool); ok {
+		opt.HashOnly = hashOnly
+	}
+
+	if hashShingleSize, ok := o["hash-shingle-size"].(int); ok {
+		opt.HashShingleSize = hashShingleSize
+	}
+
+	if hashKey, ok := o["hash-key"].(string); ok {
+		opt.HashKey = hashKey
+	}
+
+	if typeNames, ok := stringMapOption(o["type-names"]); ok {
+		opt.TypeNames = typeNames
+	}
+
+	if typePrefix, ok := o["type-prefix"].(string); ok {
+		opt.TypePrefix = typePrefix
+	}
+
+	if blobLifetime, ok := o["blob-lifetime"].(bool); ok {
+		opt.BlobLifetime = blobLifetime
+		if blobLifetime {
+			opt.AtHead = true
+		}
+	}
+
+	if atHead, ok := o["at-head"].(bool); ok && !opt.BlobLifetime {
+		opt.AtHead = atHead
+	}
+
+	if memoryLimit, ok := byteSizeOption(o["memory-limit"]); ok {
+		opt.MemoryLimit = memoryLimit
+	}
+
+	if memoryLimitAction, ok := o["memory-limit-action"].(string); ok {
+		opt.MemoryLimitAction = memoryLimitAction
+	}
+
+	if spool, ok := o["spool"].(bool); ok {
+		opt.Spool = spool
+	}
+
+	if spoolDir, ok := o["spool-dir"].(string); ok {
+		opt.SpoolDir = spoolDir
+	}
+
+	if checkpointFile, ok := o["checkpoint-file"].(string); ok {
+		opt.CheckpointFile = checkpointFile
+	}
+
+	if checkpointInterval, ok := o["checkpoint-interval"].(int); ok {
+		opt.CheckpointInterval = checkpointInterval
+	}
+
+	if maxDuration, ok := durationOption(o["max-duration"]); ok {
+		opt.MaxDuration = maxDuration
+	}
+
+	if sinceLastScan, ok := o["since-last-scan"].(string); ok {
+		opt.SinceLastScan = sinceLastScan
+	}
+
+	if resume, ok := o["resume"].(bool); ok {
+		opt.Resume = resume
+	}
+
+	if evidenceFile, ok := o["evidence-file"].(string); ok {
+		opt.EvidenceFile = evidenceFile
+	}
+
+	if evidenceKey, ok := o["evidence-key"].(string); ok {
+		opt.EvidenceKey = evidenceKey
+	}
+
+	if streamFile, ok := o["stream-file"].(string); ok {
+		opt.StreamFile = streamFile
+	}
+
+	if streamFormat, ok := o["stream-format"].(string); ok {
+		opt.StreamFormat = streamFormat
+	}
+
+	if streamContent, ok := o["stream-content"].(string); ok {
+		opt.StreamContent = streamContent
+	}
+
+	if sampleEveryN, ok := o["sample-every-n"].(int); ok {
+		opt.SampleEveryN = sampleEveryN
+	}
+
+	if samplePeriod, ok := o["sample-period"].(string); ok {
+		opt.SamplePeriod = samplePeriod
+	}
+
+	if dedupPatchId, ok := o["dedup-patch-id"].(bool); ok {
+		opt.DedupPatchId = dedupPatchId
+	}
+
+	if untrustedAuthors, ok := stringListOption(o["untrusted-authors"]); ok {
+		opt.UntrustedAuthors = untrustedAuthors
+	}
+
+	if ignoreRevs, ok := stringListOption(o["ignore-revs"]); ok {
+		opt.IgnoreRevs = ignoreRevs
+	}
+
+	if ignoreRevsFile, ok := o["ignore-revs-file"].(string); ok {
+		opt.IgnoreRevsFile = ignoreRevsFile
+	}
+
+	if scanNotesRef, ok := o["scan-notes-ref"].(string); ok {
+		opt.ScanNotesRef = scanNotesRef
+	}
+
+	if oldestFirst, ok := o["oldest-first"].(bool); ok {
+		opt.OldestFirst = oldestFirst
+	}
+
+	if sort, ok := o["sort"].(string); ok {
+		opt.Sort = sort
+		if sort == "reverse" {
+			opt.OldestFirst = true
+		}
+	}
+
+	if refCommitBudget, ok := o["ref-commit-budget"].(int); ok {
+		opt.RefCommitBudget = refCommitBudget
+	}
+
+	if roundRobinRefs, ok := o["round-robin-refs"].(bool); ok {
+		opt.RoundRobinRefs = roundRobinRefs
+	}
+
+	if since, ok := dateOption(o["since"]); ok {
+		opt.Since = since
+	}
+
+	if until, ok := untilOption(o["until"]); ok {
+		opt.Until = until
+	}
+
+	if dateField, ok := o["date-field"].(string); ok {
+		opt.DateField = dateField
+	}
+
+	return opt
+}
+
+// stringListOption accepts list options either as []string or as the
+// []interface{} produced by decoding JSON or YAML configuration.
+func stringListOption(v interface{}) ([]string, bool) {
+	switch l := v.(type) {
+	case []string:
+		return l, true
+	case []interface{}:
+		list := make([]string, 0, len(l))
+		for _, e := range l {
+			str, ok := e.(string)
+			if !ok {
+				return nil, false
+			}
+			list = append(list, str)
+		}
+		return list, true
+	case string:
+		return []string{l}, true
+	}
+
+	return nil, false
+}
+
+// stringMapOption accepts map options either as map[string]string or as the
+// map[string]interface{} produced by decoding JSON or YAML configuration.
+func stringMapOption(v interface{}) (map[string]string, bool) {
+	switch m := v.(type) {
+	case map[string]string:
+		return m, true
+	case map[string]interface{}:
+		res := make(map[string]string, len(m))
+		for k, e := range m {
+			str, ok := e.(string)
+			if !ok {
+				return nil, false
+			}
+			res[k] = str
+		}
+		return res, true
+	}
+
+	return nil, false
+}
+
+// byteSizeOption accepts sizes as numbers of bytes or as strings with a
+// unit suffix ("64KB", "512MB", "2GiB").
+func byteSizeOption(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	case string:
+		size, err := parseByteSize(n)
+		return size, err == nil
+	}
+
+	return 0, false
+}
+
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(strings.Replace(s, "IB", "B", 1), "B")
+
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		multiplier = 1 << 30
+	case strings.HasSuffix(s, "T"):
+		multiplier = 1 << 40
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return n * multiplier, nil
+}
+
+// LoadObjects loads the objects of the git repository at source (a local
+// path or a remote URL) selected by the load options. Source may also be a
+// stash entry ("stash@{1}" in the current directory, "path#stash@{1}") or a
+// git index file, whose files are returned without any history.
+//
+// Objects are returned newest first: staged files, then the history of each
+// ref in turn, from the most recent commit to the oldest by committer time.
+// With all-branches, the default branch comes first and commits shared by
+// several branches are returned with the first one. With sort set to "topo",
+// a commit is never returned before any of its children. With oldest-first,
+// the history of every ref is returned from its oldest commit, and staged
+// files come after it. Content fetched from the GitHub API
+// (pull-requests, releases, issues) always comes last.
+//
+// Spooled objects have no Content, and callers of LoadObjects only look at
+// Content: spool is refused, and loads spooling past memory-limit fail.
+// LoadObjectsWithReport and OpenObjectContent read spooled contents.
+func (s *SourceGit) LoadObjects(source string, opta seekret.LoadOptions) ([]models.Object, error) {
+	if prepareGitLoadOptions(opta).Spool {
+		return nil, fmt.Errorf("spool is not supported by LoadObjects, use LoadObjectsWithReport and OpenObjectContent")
+	}
+
+	objectList, report, err := s.LoadObjectsWithReport(source, opta)
+	if err == nil && report != nil && report.SpoolDir != "" {
+		os.RemoveAll(report.SpoolDir)
+		return nil, fmt.Errorf("loaded object content exceeds memory-limit and was spooled, which LoadObjects does not support; use LoadObjectsWithReport and OpenObjectContent, or narrow the scan")
+	}
+
+	return objectList, err
+}
+
+// LoadObjectsWithReport works like LoadObjects, and also returns a report of
+// the problems found while loading that did not abort it.
+func (s *SourceGit) LoadObjectsWithReport(source string, opta seekret.LoadOptions) ([]models.Object, *LoadReport, error) {
+	objectList, report, repo, err := s.load(source, opta)
+	if repo != nil {
+		s.releaseRepo(repo)
+	}
+
+	return objectList, report, err
+}
+
+// load loads the objects of source, and returns the repository they come
+// from still open, when there is a single one.
+func (s *SourceGit) load(source string, opta seekret.LoadOptions) ([]models.Object, *LoadReport, *git.Repository, error) {
+	return s.loadTo(source, opta, nil)
+}
+
+// loadTo works like load, but hands every object to emit, when not nil, as
+// soon as it is emitted instead of returning it.
+func (s *SourceGit) loadTo(source string, opta seekret.LoadOptions, emit func(o *models.Object) error) ([]models.Object, *LoadReport, *git.Repository, error) {
+	opt := prepareGitLoadOptions(opta)
+	source = localSource(source)
+
+	if emit != nil {
+		opt.StreamFile = ""
+	}
+
+	if isBundleSource(source) && !opt.multiRef() {
+		opt.Refs = bundleRefs
+	}
+
+	if user, ok := gistUser(source); ok {
+		objectList, report, err := s.loadUserGists(user, opt, opta)
+		if err == nil {
+			objectList, err = emitObjects(opt, objectList, emit)
+		}
+		return objectList, report, nil, err
+	}
+
+	if wiki, ok := wikiSource(source); ok && opt.IncludeWiki {
+		objectList, report, err := s.loadWithWiki(source, wiki, opta)
+		if err == nil {
+			objectList, err = emitObjects(opt, objectList, emit)
+		}
+		return objectList, report, nil, err
+	}
+
+	if opt.EvidenceFile != "" && opt.EvidenceKey == "" {
+		return nil, nil, nil, fmt.Errorf("evidence-file requires an evidence-key to sign it")
+	}
+
+	if opt.DateField != "" && opt.DateField != "committer" && opt.DateField != "author" {
+		return nil, nil, nil, fmt.Errorf("unknown date-field %q, expected \"committer\" or \"author\"", opt.DateField)
+	}
+
+	if conflicts := opt.cloneFilterConflicts(); len(cloneFilterOptions(opt)) > 0 && len(conflicts) > 0 {
+		return nil, nil, nil, fmt.Errorf("clone-blob-limit and clone-tree-filter cannot be combined with %s", strings.Join(conflicts, ", "))
+	}
+
+	if restrictions := opt.walkRestrictions(); opt.SinceLastScan != "" && len(restrictions) > 0 {
+		return nil, nil, nil, fmt.Errorf("since-last-scan cannot be combined with %s", strings.Join(restrictions, ", "))
+	}
+
+	knownSecrets, err := newKnownSecrets(opt.KnownSecrets)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if (emit != nil || opt.StreamFile != "") && (opt.BlobLifetime || opt.AtHead) {
+		return nil, nil, nil, fmt.Errorf("blob-lifetime and at-head cannot be combined with streamed objects")
+	}
+
+	collector := &objectCollector{
+		filter: s.objectFilter(),
+		redactor: s.contentRedactor(),
+		metrics: s.metricsReceiver(),
+		dedup: s.dedupCache(),
+		skipBlobs: blobSet(opt.SkipBlobs),
+		includeBlobs: blobSet(opt.IncludeBlobs),
+		knownSecrets: knownSecrets,
+		emit: emit,
+		report: &LoadReport{},
+		opt: opt,
+	}
+	defer collector.observeSince(MetricLoadDuration, time.Now())
+
+	if opt.MaxDuration > 0 {
+		collector.deadline = time.Now().Add(opt.MaxDuration)
+	}
+
+	if _, ok := profiles[opt.Profile]; opt.Profile != "" && !ok {
+		collector.report.Warnings = append(collector.report.Warnings, fmt.Sprintf("unknown profile %q ignored", opt.Profile))
+	}
+
+	if opt.EvidenceFile != "" {
+		collector.evidence = newEvidence(opt, source, opta)
+	}
+
+	if opt.Spool {
+		var err error
+		collector.spool, err = newContentSpool(opt.SpoolDir)
+		if err != nil {
+			return nil, collector.report, nil, err
+		}
+		collector.report.SpoolDir = collector.spool.dir
+	}
+
+	if opt.StreamFile != "" {
+		var err error
+		collector.stream, err = newObjectStream(opt)
+		if err != nil {
+			collector.cleanup()
+			return nil, collector.report, nil, err
+		}
+		collector.emit = collector.stream.write
+	}
+
+	// Source tarballs have no repository behind them.
+	if isArchiveSource(source) {
+		if err := objectsFromArchive(source, collector); err != nil {
+			collector.cleanup()
+			return nil, collector.report, nil, err
+		}
+		if err := collector.finish(); err != nil {
+			return nil, collector.report, nil, err
+		}
+		return collector.objects, collector.report, nil, nil
+	}
+
+	release := s.acquireClone(source)
+	openStart := time.Now()
+	opt.deadline = collector.deadline
+	repo, err := openGitRepo(source, opt)
+	release()
+	if errors.Is(err, ErrUnsupportedObjectFormat) && !isBundleSource(source) && localObjectFormat(source) == objectFormatSha256 {
+		// What was stopped at max-duration is still returned.
+		if err := objectsFromSha256(source, opt, collector); err != nil && err != errOutOfTime {
+			collector.cleanup()
+			return nil, collector.report, nil, err
+		}
+		if err := collector.finish(); err != nil {
+			return nil, collector.report, nil, err
+		}
+		return collector.objects, collector.report, nil, nil
+	}
+	if err != nil {
+		if collector.outOfTime() {
+			collector.stop("", "")
+		}
+		collector.cleanup()
+		return nil, collector.report, nil, err
+	}
+	if _, remote := normalizeGitUri(source); remote {
+		collector.observeSince(MetricCloneDuration, openStart)
+	}
+
+	s.trackRepo(repo, isTemporarySource(source))
+
+	loaded := false
+	defer func() {
+		if !loaded {
+			s.releaseRepo(repo)
+		}
+	}()
+
+	collector.report.ObjectFormat = objectFormatSha1
+	collector.partialCloneRemote = partialCloneRemote(repo)
+	collector.partialCloneDir = partialCloneDir(repo)
+
+	if fingerprint, err := repositoryFingerprint(repo); err == nil {
+		collector.fingerprint = fingerprint
+		collector.report.Fingerprint = fingerprint
+	}
+
+	if opt.Codeowners {
+		collector.codeowners, err = loadCodeowners(repo)
+		if err != nil {
+			collector.cleanup()
+			return nil, collector.report, nil, err
+		}
+	}
+
+	collector.messageNoise, err = newMessageNoise(opt)
+	if err != nil {
+		collector.cleanup()
+		return nil, collector.report, nil, err
+	}
+
+	if def, err := defaultBranch(repo); err == nil {
+		collector.report.DefaultBranch = def.Name()
+		def.Free()
+	}
+
+	collector.report.HeadRef = headRef(repo)
+	if renames, err := branchRenames(repo); err == nil {
+		collector.report.BranchRenames = renames
+		collector.report.DefaultBranchFormerNames = formerNames(collector.report.DefaultBranch, renames)
+	} else {
+		collector.report.Warnings = append(collector.report.Warnings, "reading reflog of HEAD: "+err.Error())
+	}
+
+	if opt.CheckpointFile != "" {
+		collector.checkpoint = newCheckpoint(opt.CheckpointFile, opt.CheckpointInterval, source)
+		if opt.Resume {
+			if err := collector.checkpoint.load(); err != nil {
+				collector.checkpoint = nil
+				collector.cleanup()
+				return nil, collector.report, nil, err
+			}
+		}
+	}
+
+	if opt.SinceLastScan != "" {
+		collector.scanState, err = loadScanState(opt.SinceLastScan, source)
+		if err != nil {
+			collector.cleanup()
+			return nil, collector.report, nil, err
+		}
+	}
+
+	// Staged files are newer than any commit.
+	steps := []func(*git.Repository, SourceGitLoadOptions, *objectCollector) error{
+		loadStagedObjects,
+		loadCommitObjects,
+	}
+	if opt.OldestFirst {
+		steps[0], steps[1] = steps[1], steps[0]
+	}
+	steps = append(steps, loadPullRequests, loadReleases, loadIssues)
+
+	// A bare pack has neither refs nor index to start from.
+	if isPackSource(source) {
+		steps = []func(*git.Repository, SourceGitLoadOptions, *objectCollector) error{
+			objectsFromPack,
+		}
+	}
+
+	// Snapshots are scanned on their own, without history.
+	if _, entry, ok := stashSource(source); ok {
+		steps = []func(*git.Repository, SourceGitLoadOptions, *objectCollector) error{
+			func(repo *git.Repository, opt SourceGitLoadOptions, collector *objectCollector) error {
+				return objectsFromStash(repo, entry, collector)
+			},
+		}
+	} else if isIndexFileSource(source) {
+		steps = []func(*git.Repository, SourceGitLoadOptions, *objectCollector) error{
+			func(repo *git.Repository, opt SourceGitLoadOptions, collector *objectCollector) error {
+				return objectsFromIndexFile(repo, source, collector)
+			},
+		}
+	}
+
+	for _, step := range steps {
+		if collector.outOfTime() {
+			collector.stop("", "")
+			break
+		}
+
+		err := step(repo, opt, collector)
+		if err == errOutOfTime {
+			break
+		}
+		if err != nil {
+			collector.cleanup()
+			return nil, collector.report, nil, err
+		}
+	}
 
+	if err := collector.finish(); err != nil {
+		return nil, collector.report, nil, err
+	}
 
-type SourceGitLoadOptions struct {
-	// commit-files: Include commited file content as object.
-	CommitFiles bool
-	// commit-messages: Include commit contect as object.
-	CommitMessages bool
-	// staged-files: Include stateg dile contect as object.
-	StagedFiles bool
+	loaded = true
 
-	// commit-count: Ammount of commits to analise.
-	CommitCount int
+	return collector.objects, collector.report, repo, nil
 }
 
-func prepareGitLoadOptions(o seekret.LoadOptions) SourceGitLoadOptions {
-	opt := SourceGitLoadOptions{
-		CommitFiles: false,
-		CommitMessages: false,
-		StagedFiles: false,
+func loadCommitObjects(repo *git.Repository, opt SourceGitLoadOptions, collector *objectCollector) error {
+	if !opt.CommitFiles && !opt.CommitMessages && !opt.DeletedFiles && !opt.CommitMetadata && !opt.CommitDiffs {
+		return nil
+	}
 
-		CommitCount: 0,
+	// What was walked before max-duration is still completed.
+	stopped := objectsFromCommit(repo, opt, collector)
+	if stopped != nil && stopped != errOutOfTime {
+		return stopped
 	}
 
-	if commit, ok := o["commit-files"].(bool); ok {
-		opt.CommitFiles = commit
+	err := collector.resolvePromised(repo)
+	if err != nil {
+		return err
 	}
 
-	if commitMessages, ok := o["commit-messages"].(bool); ok {
-		opt.CommitMessages = commitMessages
+	collector.annotateTimezones()
+
+	if opt.BlobLifetime {
+		collector.annotateLifetimes()
 	}
 
-	if stagedFiles, ok := o["staged-files"].(bool); ok {
-		opt.StagedFiles = stagedFiles
+	if opt.AtHead {
+		if err := collector.annotateAtHead(repo); err != nil {
+			return err
+		}
 	}
 
-	if commitCount, ok := o["commit-count"].(int); ok {
-		opt.CommitCount = commitCount
+	return stopped
+}
+
+func loadStagedObjects(repo *git.Repository, opt SourceGitLoadOptions, collector *objectCollector) error {
+	if opt.WorktreeChanges {
+		objectListWorktree, err := objectsFromWorktreeChanges(repo, opt, collector)
+		if err != nil {
+			return err
+		}
+		if err := collector.add(objectListWorktree...); err != nil {
+			return err
+		}
 	}
 
-	return opt
+	if !opt.StagedFiles {
+		return nil
+	}
+
+	objectListStagedFiles, err := objectsFromStagedFiles(repo, collector)
+	if err != nil {
+		return err
+	}
+
+	return collector.add(objectListStagedFiles...)
 }
 
-func (s *SourceGit) LoadObjects(source string, opta seekret.LoadOptions) ([]models.Object, error) {
-	var objectList []models.Object
+func objectsFromCommit(repo *git.Repository, opt SourceGitLoadOptions, collector *objectCollector) error {
+	refs, err := collectRefs(repo, opt, collector.report)
+	if err != nil {
+		if isUnbornBranch(err) {
+			return &SourceError{Kind: ErrEmptyRepo, Source: repo.Path(), Err: err}
+		}
+		return err
+	}
 
-	opt := prepareGitLoadOptions(opta)
+	if err := hideCommits(refs, opt.ExcludeCommits); err != nil {
+		return err
+	}
+	collector.scanState.hide(repo, refs)
 
-	repo, err := openGitRepo(source)
+	collector.evidence.refs(refs)
+
+	walker, err := newHistoryWalker(repo, opt, collector.report)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if opt.CommitFiles && opt.CommitMessages {
-		objectListCommit,err := objectsFromCommit(repo, opt.CommitFiles, opt.CommitMessages, opt.CommitCount)
+	sampler := newCommitSampler(opt)
+
+	if opt.Mailmap {
+		collector.mailmap, err = loadMailmap(repo)
 		if err != nil {
-			return nil,err
+			return err
+		}
+	}
+
+	ignored, err := loadIgnoreRevs(repo, opt)
+	if err != nil {
+		return err
+	}
+
+	if opt.ScanNotesRef != "" {
+		if ref, err := repo.References.Lookup(opt.ScanNotesRef); err == nil {
+			ref.Free()
+		} else if err := fetchScanNotes(repo, opt.ScanNotesRef); err != nil {
+			collector.report.Warnings = append(collector.report.Warnings, "fetching "+opt.ScanNotesRef+": "+err.Error())
+		}
+	}
+
+	// Commits reachable from several refs are only emitted for the first one,
+	// and so are cherry-picks of the same change with dedup-patch-id.
+	seen := make(map[string]bool)
+	seenPatches := make(map[string]bool)
+	if collector.checkpoint != nil {
+		for _, id := range collector.checkpoint.state.Commits {
+			seen[id] = true
+		}
+	}
+
+	// scan emits the objects of a commit walked from ref, depth commits
+	// from its tip.
+	scan := func(ref scanRef, commit *git.Commit, depth int) error {
+		if collector.outOfTime() {
+			return collector.stop(ref.Name, commit.Id().String())
 		}
-		objectList = append(objectList, objectListCommit...)
+
+		if seen[commit.Id().String()] {
+			return nil
+		}
+		seen[commit.Id().String()] = true
+
+		if ignored[commit.Id().String()] {
+			return nil
+		}
+
+		if opt.ScanNotesRef != "" && scannedCommit(repo, opt.ScanNotesRef, commit) {
+			return nil
+		}
+
+		if !inDateRange(commit, opt) {
+			return nil
+		}
+
+		if !sampler.keep(commit) {
+			return nil
+		}
+
+		if len(opt.UntrustedAuthors) > 0 && trustedCommit(commit, opt.UntrustedAuthors, collector.mailmap) {
+			return nil
+		}
+
+		if opt.DedupPatchId {
+			id, err := patchId(repo, commit)
+			if err != nil {
+				return err
+			}
+			if id != "" {
+				if seenPatches[id] {
+					collector.report.DuplicateCommits++
+					return nil
+				}
+				seenPatches[id] = true
+			}
+		}
+
+		objectListSingle, err := objectsFromSingleCommit(repo, commit, opt, collector)
+		if err != nil {
+			return err
+		}
+
+		setAncestryMetadata(objectListSingle, commit, depth)
+		setIdentityMetadata(objectListSingle, commit, collector.mailmap)
+		collector.timezones.add(authorTimezone(commit))
+
+		if replaced, ok := walker.replacedBy[commit.Id().String()]; ok {
+			for i := range objectListSingle {
+				objectListSingle[i].SetMetadata("replaces", replaced, models.MetadataAttributes{})
+			}
+		}
+
+		if opt.multiRef() {
+			for i := range objectListSingle {
+				setRefMetadata(&objectListSingle[i], ref)
+			}
+		}
+
+		if ref.Fork != "" {
+			for i := range objectListSingle {
+				objectListSingle[i].SetMetadata("fork", ref.Fork, models.MetadataAttributes{})
+				objectListSingle[i].SetMetadata("branch", ref.Name, models.MetadataAttributes{})
+			}
+		}
+		err = collector.add(objectListSingle...)
+		if err != nil {
+			return err
+		}
+		collector.evidence.commit(commit.Id().String())
+
+		if collector.checkpoint != nil {
+			err = collector.checkpoint.done(commit.Id().String())
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if opt.RefCommitBudget > 0 || opt.RoundRobinRefs {
+		return walkRefsBudgeted(repo, walker, refs, opt, collector, scan)
 	}
 
-	if opt.StagedFiles {
-		objectListStagedFiles,err := objectsFromStagedFiles(repo)
+	for _, ref := range refs {
+		var walkErr error
+
+		count := 0
+		err = walker.walk(ref, func(commit *git.Commit) bool {
+			if opt.CommitCount > 0 && count >= opt.CommitCount {
+				return false
+			}
+			count++
+			collector.metricAdd(MetricCommitsWalked, 1)
+
+			walkErr = scan(ref, commit, walker.depth(commit))
+			return walkErr == nil
+		})
+
 		if err != nil {
-			return nil,err
+			return err
+		}
+
+		if walkErr != nil {
+			return walkErr
 		}
-		objectList = append(objectList, objectListStagedFiles...)
 	}
 
-	return objectList, nil
+	return nil
 }
 
-func objectsFromCommit(repo *git.Repository, commitFiles bool, commitMessages bool, count int) ([]models.Object, error) {
+// setAncestryMetadata records where in history the objects of a commit come
+// from: the commit parents and its depth from the tip of the scanned ref.
+func setAncestryMetadata(objectList []models.Object, commit *git.Commit, depth int) {
+	parents := make([]string, 0, commit.ParentCount())
+	for i := uint(0); i < commit.ParentCount(); i++ {
+		parents = append(parents, commit.ParentId(i).String())
+	}
+
+	for i := range objectList {
+		objectList[i].SetMetadata("parents", strings.Join(parents, ","), models.MetadataAttributes{})
+		objectList[i].SetMetadata("depth", strconv.Itoa(depth), models.MetadataAttributes{})
+	}
+}
+
+func objectsFromSingleCommit(repo *git.Repository, commit *git.Commit, opt SourceGitLoadOptions, collector *objectCollector) ([]models.Object, error) {
 	var objectList []models.Object
 
-	walk, err := repo.Walk()
-	if err != nil {
-		return nil, err
+	if len(opt.Pathspec) > 0 {
+		touched, err := commitTouchesPathspec(repo, commit, opt.Pathspec)
+		if err != nil {
+			return nil, err
+		}
+		if !touched {
+			return nil, nil
+		}
+	}
+
+	if opt.CommitMessages && !collector.messageNoise.noise(commit) {
+		objectList = append(objectList, objectsFromCommitMessage(commit, opt)...)
 	}
 
-	if count > 0 {
-		err := walk.PushRange(fmt.Sprintf("HEAD~%d..HEAD", count))
+	if opt.CommitMetadata {
+		o, err := objectFromCommitMetadata(repo, commit, collector.mailmap)
 		if err != nil {
-			err := walk.PushHead()
-			if err != nil {
-				return nil,err
-			}
+			return nil, err
 		}
-	} else {
-		err := walk.PushHead()
+		objectList = append(objectList, *o)
+	}
+
+	if opt.CommitFiles {
+		objectListFiles, err := objectsFromCommitTree(repo, commit, opt, collector)
 		if err != nil {
-			return nil,err
+			return nil, err
 		}
+		objectList = append(objectList, objectListFiles...)
 	}
-	walk.Sorting(git.SortTime)
 
-	err = walk.Iterate(func(commit *git.Commit) bool {
-		tree, err := commit.Tree()
+	if opt.CommitDiffs {
+		objectListDiffs, err := objectsFromCommitDiff(repo, commit, opt, collector)
 		if err != nil {
-			fmt.Println(err)
+			return nil, err
 		}
+		objectList = append(objectList, objectListDiffs...)
+	}
 
-		if commitMessages {
-			o := models.NewObject(fmt.Sprintf("commit-%s", commit.Id()), Type, "commit-message", []byte(commit.Message()))
-			o.SetMetadata("commit", commit.Id().String(), models.MetadataAttributes{})
-			objectList = append(objectList, *o)
+	if opt.DeletedFiles {
+		objectListDeleted, err := objectsFromDeletedFiles(repo, commit, opt.Pathspec, collector)
+		if err != nil {
+			return nil, err
+		}
+		objectList = append(objectList, objectListDeleted...)
+	}
+
+	return objectList, nil
+}
+
+func objectsFromCommitTree(repo *git.Repository, commit *git.Commit, opt SourceGitLoadOptions, collector *objectCollector) ([]models.Object, error) {
+	var objectList []models.Object
+	var walkErr error
+
+	// Paths in the tree that only differ in case, which collide when
+	// checked out on case-insensitive filesystems.
+	collisions := make(casePaths)
+
+	// Trees are only loaded when file contents are requested, so
+	// message-only scans never touch them.
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, collector.corrupt(commit.TreeId().String(), "", commit.Id().String(), err)
+	}
+	defer tree.Free()
+
+	err = git.WalkTree(tree, func(base string, tentry *git.TreeEntry) int {
+		if tentry.Type == git.ObjectTree && collector.tooDeep(base+tentry.Name, true) {
+			return 1
+		}
+
+		if len(opt.Pathspec) > 0 {
+			if tentry.Type == git.ObjectTree && !pathspecMayMatchDir(base+tentry.Name, opt.Pathspec) {
+				return 1
+			}
+			if tentry.Type == git.ObjectBlob && !matchPathspec(base+tentry.Name, opt.Pathspec) {
+				return 0
+			}
 		}
 
+		if tentry.Type == git.ObjectBlob {
+			collisions.add(base + tentry.Name)
 
-		if commitFiles {
-			// TODO: what to return?
-			tree.Walk(func(base string, tentry *git.TreeEntry) int {
-				if tentry.Type == git.ObjectBlob {
-					blob, err := repo.LookupBlob(tentry.Id)
-					if err != nil {
-						return 0
-					}	
+			if opt.BlobLifetime {
+				collector.lifetimes.see(tentry.Id.String(), commit)
+			}
+
+			if collector.skippedBlob(tentry.Id.String()) {
+				return 0
+			}
 
-					o := models.NewObject(fmt.Sprintf("%s%s", base, tentry.Name), Type, "file-content", blob.Contents())
+			if collector.excludedPath(base+tentry.Name) && !collector.includedBlob(tentry.Id.String()) {
+				return 0
+			}
+
+			if opt.SkipGenerated && collector.generatedPath(base+tentry.Name) && !collector.includedBlob(tentry.Id.String()) {
+				return 0
+			}
 
-					o.SetMetadata("commit", commit.Id().String(), models.MetadataAttributes{})
-					o.SetMetadata("uniq-id", tentry.Id.String(), models.MetadataAttributes{
-						PrimaryKey: true,
-					})
-					objectList = append(objectList, *o)
+			if opt.MetadataOnly {
+				o, err := metadataOnlyObject(repo, base+tentry.Name, tentry.Id)
+				if err != nil && collector.isPromised(err) {
+					// Not fetched: the size is unknown.
+					o, err = models.NewObject(base+tentry.Name, Type, "file-content", nil), nil
+				}
+				if err != nil {
+					walkErr = collector.corrupt(tentry.Id.String(), base+tentry.Name, commit.Id().String(), err)
+					if walkErr != nil {
+						return -1
+					}
+					return 0
 				}
+				o.SetMetadata("commit", commit.Id().String(), models.MetadataAttributes{})
+				setUniqId(o, tentry.Id.String())
+				objectList = append(objectList, *o)
+				return 0
+			}
 
+			if opt.IncrementalContent && collector.incremental.unchanged(base+tentry.Name, tentry.Id.String()) {
+				return 0
+			}
+
+			blob, err := repo.LookupBlob(tentry.Id)
+			if err != nil && collector.isPromised(err) {
+				// Content is filled in once the walk is over.
+				o := models.NewObject(fmt.Sprintf("%s%s", base, tentry.Name), Type, "file-content", nil)
+				o.SetMetadata("commit", commit.Id().String(), models.MetadataAttributes{})
+				setUniqId(o, tentry.Id.String())
+				collector.promise(tentry.Id.String())
+				objectList = append(objectList, *o)
+				return 0
+			}
+			if err != nil {
+				walkErr = collector.corrupt(tentry.Id.String(), base+tentry.Name, commit.Id().String(), err)
+				if walkErr != nil {
+					return -1
+				}
 				return 0
-			})
+			}
+
+			content := blob.Contents()
+			blob.Free()
+
+			var baseBlob string
+			if opt.IncrementalContent {
+				content, baseBlob = collector.incremental.update(base+tentry.Name, tentry.Id.String(), content)
+				if baseBlob != "" && len(content) == 0 {
+					return 0
+				}
+			}
+
+			o := models.NewObject(fmt.Sprintf("%s%s", base, tentry.Name), Type, "file-content", content)
+
+			o.SetMetadata("commit", commit.Id().String(), models.MetadataAttributes{})
+			setUniqId(o, tentry.Id.String())
+			if baseBlob != "" {
+				o.SetMetadata("content-mode", "incremental", models.MetadataAttributes{})
+				o.SetMetadata("base-blob", baseBlob, models.MetadataAttributes{})
+			}
+			objectList = append(objectList, *o)
 		}
 
-		return true
+		return 0
 	})
 
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	collisions.annotate(objectList)
+
 	if err != nil {
-		return nil, err
+		// A subtree could not be read: keep what was collected so far.
+		err = collector.corrupt(tree.Id().String(), "", commit.Id().String(), err)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return objectList, nil
 }
 
-
-func objectsFromStagedFiles(repo *git.Repository) ([]models.Object, error) {
+func objectsFromStagedFiles(repo *git.Repository, collector *objectCollector) ([]models.Object, error) {
 	var objectList []models.Object
 
 	index, err := repo.Index()
 	if err != nil {
 		return nil,err
 	}
+	defer index.Free()
 
-	for i := 0; i < int(index.EntryCount()); i++ {
-
-		entry, err := index.EntryByIndex(uint(i))
+	conflicted := make(map[string]bool)
+	if index.HasConflicts() {
+		objectListConflicts, err := objectsFromConflicts(repo, index, conflicted, collector)
 		if err != nil {
 			return nil,err
 		}
+		objectList = append(objectList, objectListConflicts...)
+	}
+
+	statusList, err := repo.StatusList(&git.StatusOptions{
+		Show:  git.StatusShowIndexOnly,
+		Flags: git.StatusOptRenamesHeadToIndex,
+	})
+	if err != nil {
+		return nil,err
+	}
+	defer statusList.Free()
+
+	count, err := statusList.EntryCount()
+	if err != nil {
+		return nil,err
+	}
 
-		status, err := repo.StatusFile(entry.Path)
+	for i := 0; i < count; i++ {
+		entry, err := statusList.ByIndex(i)
 		if err != nil {
 			return nil,err
 		}
 
-		if status != git.StatusCurrent {
-			blob, err := repo.LookupBlob(entry.Id)
+		status := stagedStatusName(entry.Status)
+		if status == "" {
+			continue
+		}
+
+		// Deleted entries only exist on the HEAD side of the delta.
+		file := entry.HeadToIndex.NewFile
+		if status == "deleted" {
+			file = entry.HeadToIndex.OldFile
+		}
+
+		if conflicted[file.Path] {
+			continue
+		}
+
+		blob, err := repo.LookupBlob(file.Oid)
+		if err != nil {
+			err = collector.corrupt(file.Oid.String(), file.Path, "", err)
 			if err != nil {
 				return nil,err
 			}
+			continue
+		}
 
-			o := models.NewObject(entry.Path, Type, "file-content", blob.Contents())
+		o := models.NewObject(file.Path, Type, "file-content", blob.Contents())
+		blob.Free()
 
-			// TODO: Type of staged.
-			o.SetMetadata("status", "staged", models.MetadataAttributes{})
-			objectList = append(objectList, *o)
+		setUniqId(o, file.Oid.String())
+		o.SetMetadata("status", status, models.MetadataAttributes{})
+		if status == "renamed" {
+			o.SetMetadata("old-path", entry.HeadToIndex.OldFile.Path, models.MetadataAttributes{})
 		}
+		objectList = append(objectList, *o)
 	}
 
-	return objectList,nil
-}
-
-
-func credentialsCallback(gitUri string, username string, allowedTypes git.CredType) (git.ErrorCode, *git.Cred) {
-	sshConfigFile := os.ExpandEnv("$HOME/.ssh/config")
-
-	fh, err := os.Open(sshConfigFile)
+	objectListMergeState, err := objectsFromMergeState(repo)
 	if err != nil {
-		panic(err)
+		return nil,err
 	}
+	objectList = append(objectList, objectListMergeState...)
 
-	c, err := sshconfig.Parse(fh)
-	if err != nil {
-		panic(err)
-	}
+	return objectList,nil
+}
 
-	fh.Close()
 
-	u, err := url.Parse(gitUri)
-	if err != nil {
-		panic(err)
+// stagedStatusName maps the index side of a status entry to the value of the
+// "status" metadata. It returns an empty string for entries without staged
+// changes.
+func stagedStatusName(status git.Status) string {
+	switch {
+	case status&git.StatusIndexRenamed != 0:
+		return "renamed"
+	case status&git.StatusIndexNew != 0:
+		return "new"
+	case status&git.StatusIndexDeleted != 0:
+		return "deleted"
+	case status&git.StatusIndexTypeChange != 0:
+		return "typechange"
+	case status&git.StatusIndexModified != 0:
+		return "modified"
 	}
 
-	host := c.FindByHostname(u.Host)
-	idFile := host.GetParam("IdentityFile").Value()
-	idFilePub := idFile + ".pub"
-
-	ret, cred := git.NewCredSshKey("git", idFilePub, idFile, "")
+	return ""
+}
 
-	return git.ErrorCode(ret), &cred
+// newRemoteCallbacks returns the callbacks used for every network operation,
+// on a remote given as the ssh_config Host sshAlias, if not empty.
+func newRemoteCallbacks(sshAlias string) git.RemoteCallbacks {
+	return git.RemoteCallbacks{
+		CredentialsCallback:      newCredentialsCallback(sshAlias),
+		CertificateCheckCallback: certificateCheckCallback,
+	}
 }
 
-func certificateCheckCallback(cert *git.Certificate, valid bool, hostname string) git.ErrorCode {
-	return 0
+func certificateCheckCallback(cert *git.Certificate, valid bool, hostname string) git.CallbackResult {
+	return callbackOk
 }
 
 func normalizeGitUri(source string) (string, bool) {
 	var gitUri string
 
+	source = insteadOf(source)
+
+	if sshUri, _, ok := sshConfigUri(source); ok {
+		return sshUri, true
+	}
+
 	gitregexp := regexp.MustCompile("^(?:(https?|git|ssh)://|(git@))([^:|/]+)(?:/|:)([^/]+)/([^/\\.]+)(.git)$")
 	u := gitregexp.FindStringSubmatch(source)
 
@@ -256,48 +1833,114 @@ func normalizeGitUri(source string) (string, bool) {
 	return gitUri, true
 }
 
-func openGitRepo(source string) (*git.Repository, error) {
+func openGitRepo(source string, opt SourceGitLoadOptions) (*git.Repository, error) {
 	var repo *git.Repository
 
+	if isBundleSource(source) {
+		return openGitRepoBundle(source)
+	}
+
+	if isPackSource(source) {
+		return openGitRepoPack(source)
+	}
+
+	if _, _, ok := stashSource(source); ok || isIndexFileSource(source) {
+		return openGitRepoSnapshot(source, opt)
+	}
+
 	gitUri, remote := normalizeGitUri(source)
+	alias := sshSourceAlias(source)
+	if cloneUrl, ok := gistCloneUrl(source); ok {
+		gitUri, remote, alias = cloneUrl, true, ""
+	}
+
+	// libgit2 would not go through the bastion, present the client
+	// certificate, refuse redirects, nor do shallow or partial clones, and
+	// runs in-process.
+	redirectOptions := strictRedirectOptions(gitUri, opt)
+	filterOptions := cloneFilterOptions(opt)
+	cli := opt.HeadOnly || sshProxied(gitUri, alias) || opt.ClientCert != "" || len(redirectOptions) > 0 || len(filterOptions) > 0 || opt.SandboxClone
+
+	if remote && cli {
+		cliOptions := append(append(tlsCloneOptions(opt), redirectOptions...), filterOptions...)
+
+		// Only HTTPS transfers of the git CLI can be throttled.
+		if opt.MaxBandwidth > 0 && strings.HasPrefix(gitUri, "https://") {
+			return openGitRepoRemoteThrottled(gitUri, opt, cliOptions...)
+		}
+
+		if opt.HeadOnly {
+			return openGitRepoRemoteShallow(gitUri, opt, cliOptions...)
+		}
+		return openGitRepoRemoteCli(gitUri, opt, cliOptions...)
+	}
 
 	if remote {
-		return openGitRepoRemote(gitUri)
+		return openGitRepoRemote(gitUri, alias, opt)
 	} else {
-		return openGitRepoLocal(source)
+		return openGitRepoLocal(source, opt)
 	}
 
 	return repo, nil
 }
 
-func openGitRepoRemote(gitUri string) (*git.Repository, error) {
+func openGitRepoRemote(gitUri string, alias string, opt SourceGitLoadOptions) (*git.Repository, error) {
 	var repo *git.Repository
 	var err error
 
-	tmpdir, err := ioutil.TempDir("", "seekret")
+	callbacks := newRemoteCallbacks(alias)
+	if opt.CaBundle != "" {
+		callbacks.CertificateCheckCallback, err = caCertificateCheck(opt.CaBundle)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opt.MaxBandwidth > 0 {
+		throttleCallbacks(&callbacks, opt.MaxBandwidth)
+	}
+	deadlineCallbacks(&callbacks, opt.deadline)
+
+	tmpdir, err := tempDir("clone", gitUri)
 	if err != nil {
 		return nil, err
 	}
 
-	repo, err = git.Clone(gitUri, tmpdir, &git.CloneOptions{
-		FetchOptions: &git.FetchOptions{
-			RemoteCallbacks: git.RemoteCallbacks{
-				CredentialsCallback:      credentialsCallback,
-				CertificateCheckCallback: certificateCheckCallback,
-			},
-		},
-	})
+	repo, err = git.CloneNoCheckout(gitUri, tmpdir, callbacks)
 	if err != nil {
+		removeTempDir(tmpdir)
+		if pastDeadline(opt.deadline) {
+			return nil, fmt.Errorf("cloning %s: %v", gitUri, errOutOfTime)
+		}
+		return nil, remoteError(gitUri, err)
+	}
+
+	if err := indexHead(repo); err != nil {
+		repo.Free()
+		removeTempDir(tmpdir)
 		return nil, err
 	}
 
 	return repo, nil
 }
 
-func openGitRepoLocal(source string) (*git.Repository, error) {
-	repo, err := git.OpenRepositoryExtended(source, git.RepositoryOpenCrossFs, "")
+func openGitRepoLocal(source string, opt SourceGitLoadOptions) (*git.Repository, error) {
+	flags := git.RepositoryOpenCrossFs
+	if opt.NoSearchParents {
+		flags |= git.RepositoryOpenNoSearch
+	}
+
+	if !opt.IgnoreGitEnv && gitEnvSet() {
+		flags |= openFromEnv
+	}
+
+	ceiling := strings.Join(opt.CeilingDirectories, string(os.PathListSeparator))
+
+	repo, err := git.OpenRepositoryExtended(source, flags, ceiling)
 	if  err != nil{
-		return nil, err
+		if remote := promisorRemote(source); remote != "" {
+			return openPartialClone(source, remote)
+		}
+		return nil, localOpenError(source, err)
 	}
 
 	return repo, nil
@@ -0,0 +1,162 @@
+package sourcegit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apuigsech/seekret"
+	"github.com/apuigsech/seekret/models"
+)
+
+// testGit runs the git CLI in dir, isolated from the configuration of the
+// user running the tests.
+func testGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "HOME="+dir,
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+// testRepo creates a repository with one commit per file content.
+func testRepo(t *testing.T, contents ...string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "sourcegit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	testGit(t, dir, "init", "--quiet")
+	for i, content := range contents {
+		if err := ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		testGit(t, dir, "add", ".")
+		testGit(t, dir, "commit", "--quiet", "-m", fmt.Sprintf("commit %d", i))
+	}
+
+	return dir
+}
+
+type testMetrics struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (m *testMetrics) Add(name string, delta int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[name] += delta
+}
+
+func (m *testMetrics) Observe(name string, d time.Duration) {}
+
+// TestConcurrentLoads loads many repositories in parallel from one SourceGit
+// while its settings change. Run it with -race.
+func TestConcurrentLoads(t *testing.T) {
+	const repos = 8
+
+	sources := make([]string, repos)
+	for i := range sources {
+		sources[i] = testRepo(t, fmt.Sprintf("repo %d\n", i), fmt.Sprintf("repo %d\npassword=%d\n", i, i))
+	}
+
+	s := &SourceGit{}
+	s.SetMetrics(&testMetrics{counts: make(map[string]int64)})
+	s.SetConcurrencyLimits(ConcurrencyLimits{Total: 4})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, repos)
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source string) {
+			defer wg.Done()
+
+			objects, report, err := s.LoadObjectsWithReport(source, seekret.LoadOptions{
+				"commit-files":    true,
+				"commit-messages": true,
+			})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if report == nil || len(objects) == 0 {
+				errs <- fmt.Errorf("%s: no objects loaded", source)
+				return
+			}
+
+			want := fmt.Sprintf("repo %d\n", i)
+			for _, o := range objects {
+				if o.SubType == "file-content" && !strings.HasPrefix(string(o.Content), want) {
+					errs <- fmt.Errorf("%s: %s holds %q, from another repository", source, o.Name, o.Content)
+					return
+				}
+			}
+		}(i, source)
+	}
+
+	// Settings are swapped under running loads.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			s.SetObjectFilter(func(o *models.Object) bool { return true })
+			s.SetRedactor(nil)
+			s.SetDedupCache(nil)
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    int64
+		wantErr bool
+	}{
+		{"100", 100, false},
+		{"100B", 100, false},
+		{"1K", 1 << 10, false},
+		{"1KiB", 1 << 10, false},
+		{"512MB", 512 << 20, false},
+		{" 3 mb ", 3 << 20, false},
+		{"2g", 2 << 30, false},
+		{"1TB", 1 << 40, false},
+		{"", 0, true},
+		{"1.5M", 0, true},
+		{"MB", 0, true},
+		{"ten", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := parseByteSize(test.s)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseByteSize(%q) = %d, want an error", test.s, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("parseByteSize(%q) = %d, %v, want %d", test.s, got, err, test.want)
+		}
+	}
+}
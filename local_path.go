@@ -0,0 +1,66 @@
+package sourcegit
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localSource turns file:// URLs into paths and relative paths (including
+// ".", and the repository of stash sources) into absolute ones. Remote URLs
+// are returned unchanged.
+func localSource(source string) string {
+	if strings.HasPrefix(source, "file://") {
+		if u, err := url.Parse(source); err == nil && (u.Host == "" || u.Host == "localhost") {
+			source = u.Path
+		}
+	}
+
+	if path, entry, ok := stashSource(source); ok {
+		return localSource(path) + "#" + entry
+	}
+
+	if _, remote := normalizeGitUri(source); remote || strings.Contains(source, "://") {
+		return source
+	}
+	if gistRegexp.MatchString(source) || gistUserRegexp.MatchString(source) {
+		return source
+	}
+
+	if abs, err := filepath.Abs(source); err == nil {
+		return abs
+	}
+
+	return source
+}
+
+// localOpenError explains why the repository at path could not be opened,
+// telling a missing path apart from a path outside of any repository, and
+// from repository formats libgit2 cannot read (SHA-256).
+func localOpenError(path string, err error) error {
+	if _, statErr := os.Stat(path); os.IsNotExist(statErr) {
+		return &SourceError{Kind: ErrRepoNotFound, Source: path, Err: fmt.Errorf("path does not exist")}
+	}
+	if formatErr := checkObjectFormat(path, localObjectFormat(path)); formatErr != nil {
+		return formatErr
+	}
+	if isNotFound(err) {
+		return &SourceError{Kind: ErrNotAGitRepo, Source: path, Err: err}
+	}
+
+	return err
+}
+
+// gitEnvSet tells whether the environment points git at a repository layout
+// other than the one found from the source path.
+func gitEnvSet() bool {
+	for _, name := range []string{"GIT_DIR", "GIT_WORK_TREE", "GIT_INDEX_FILE"} {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,146 @@
+package sourcegit
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// mailmap canonicalizes author and committer identities like git does with
+// .mailmap. A nil mailmap leaves identities unchanged.
+type mailmap struct {
+	entries []mailmapEntry
+}
+
+type mailmapEntry struct {
+	properName  string
+	properEmail string
+	commitName  string
+	commitEmail string
+}
+
+// loadMailmap reads the .mailmap of the working directory, or the one at
+// HEAD for bare repositories (as with git's mailmap.blob default) and for
+// working directories without one, such as the ones of remote clones.
+func loadMailmap(repo *git.Repository) (*mailmap, error) {
+	if !repo.IsBare() {
+		// git ignores a .mailmap that is a symbolic link, too.
+		p, err := checkoutPath(repo.Workdir(), ".mailmap")
+		if err != nil {
+			return nil, nil
+		}
+
+		data, err := ioutil.ReadFile(p)
+		if err == nil {
+			return parseMailmap(data), nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		// Remote sources are cloned without checking out their files.
+	}
+
+	data, err := headFile(repo, ".mailmap")
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	return parseMailmap(data), nil
+}
+
+// parseMailmap parses the lines of a .mailmap file:
+//
+//	Proper Name <commit@email>
+//	<proper@email> <commit@email>
+//	Proper Name <proper@email> <commit@email>
+//	Proper Name <proper@email> Commit Name <commit@email>
+func parseMailmap(data []byte) *mailmap {
+	m := &mailmap{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		var names, emails []string
+		for {
+			open := strings.Index(line, "<")
+			end := strings.Index(line, ">")
+			if open < 0 || end < open {
+				break
+			}
+			names = append(names, strings.TrimSpace(line[:open]))
+			emails = append(emails, strings.TrimSpace(line[open+1:end]))
+			line = line[end+1:]
+		}
+
+		switch len(emails) {
+		case 1:
+			m.entries = append(m.entries, mailmapEntry{
+				properName:  names[0],
+				commitEmail: emails[0],
+			})
+		case 2:
+			m.entries = append(m.entries, mailmapEntry{
+				properName:  names[0],
+				properEmail: emails[0],
+				commitName:  names[1],
+				commitEmail: emails[1],
+			})
+		}
+	}
+
+	return m
+}
+
+// resolve returns the canonical name and email for an identity. Entries
+// matching both name and email win over the ones matching the email only.
+func (m *mailmap) resolve(name, email string) (string, string) {
+	if m == nil {
+		return name, email
+	}
+
+	var match *mailmapEntry
+	for i := range m.entries {
+		e := &m.entries[i]
+		if !strings.EqualFold(e.commitEmail, email) {
+			continue
+		}
+		if e.commitName == "" {
+			if match == nil {
+				match = e
+			}
+			continue
+		}
+		if strings.EqualFold(e.commitName, name) {
+			match = e
+			break
+		}
+	}
+
+	if match == nil {
+		return name, email
+	}
+	if match.properName != "" {
+		name = match.properName
+	}
+	if match.properEmail != "" {
+		email = match.properEmail
+	}
+
+	return name, email
+}
+
+// signature returns the canonical form of a commit signature.
+func (m *mailmap) signature(sig *git.Signature) commitSignature {
+	s := newCommitSignature(sig)
+	s.Name, s.Email = m.resolve(s.Name, s.Email)
+
+	return s
+}
@@ -0,0 +1,35 @@
+package sourcegit
+
+import (
+	"testing"
+)
+
+func TestParseMailmap(t *testing.T) {
+	data := []byte(`# Canonical identities.
+Proper Name <commit@example.com>
+<proper@example.com> <old@example.com>
+Other Name <other@example.com> <shared@example.com>
+Right Name <right@example.com> Wrong Name <shared@example.com> # trailing comment
+not an entry
+`)
+	m := parseMailmap(data)
+
+	tests := []struct {
+		name, email         string
+		wantName, wantEmail string
+	}{
+		{"commit", "commit@example.com", "Proper Name", "commit@example.com"},
+		{"commit", "COMMIT@example.com", "Proper Name", "COMMIT@example.com"},
+		{"old", "old@example.com", "old", "proper@example.com"},
+		{"Anyone", "shared@example.com", "Other Name", "other@example.com"},
+		{"wrong name", "shared@example.com", "Right Name", "right@example.com"},
+		{"unknown", "unknown@example.com", "unknown", "unknown@example.com"},
+	}
+
+	for _, test := range tests {
+		name, email := m.resolve(test.name, test.email)
+		if name != test.wantName || email != test.wantEmail {
+			t.Errorf("resolve(%q, %q) = %q, %q, want %q, %q", test.name, test.email, name, email, test.wantName, test.wantEmail)
+		}
+	}
+}
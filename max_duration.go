@@ -0,0 +1,110 @@
+package sourcegit
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// errOutOfTime stops a load reaching max-duration. It only reaches the
+// caller when the clone itself did not complete in time: otherwise the load
+// returns what it collected until then.
+var errOutOfTime = errors.New("max-duration reached")
+
+// StopPoint is where a load cut short by max-duration stopped.
+type StopPoint struct {
+	// Ref being walked and first commit of it left unscanned, both empty
+	// when the load stopped outside of the history walk. Commit is empty
+	// when it stopped while listing the commits of Ref, with
+	// ref-commit-budget or round-robin-refs, before scanning any.
+	Ref    string
+	Commit string
+}
+
+// outOfTime reports whether max-duration is reached.
+func (c *objectCollector) outOfTime() bool {
+	return pastDeadline(c.deadline)
+}
+
+// pastDeadline reports whether deadline, if not zero, has passed.
+func pastDeadline(deadline time.Time) bool {
+	return !deadline.IsZero() && time.Now().After(deadline)
+}
+
+// deadlineCallbacks aborts a libgit2 clone or fetch once deadline, if not
+// zero, has passed. The transfer progress callback runs in the download
+// loop, so it is wrapped, keeping what it already does (max-bandwidth).
+func deadlineCallbacks(callbacks *git.RemoteCallbacks, deadline time.Time) {
+	if deadline.IsZero() {
+		return
+	}
+
+	progress := callbacks.TransferProgressCallback
+	callbacks.TransferProgressCallback = func(stats git.TransferProgress) git.CallbackResult {
+		if pastDeadline(deadline) {
+			return callbackAbort
+		}
+		if progress != nil {
+			return progress(stats)
+		}
+		return callbackOk
+	}
+}
+
+// outputUntil runs cmd like CombinedOutput, killing it once deadline, if not
+// zero, has passed, in which case it returns errOutOfTime.
+func outputUntil(cmd *exec.Cmd, deadline time.Time) ([]byte, error) {
+	if deadline.IsZero() {
+		return cmd.CombinedOutput()
+	}
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	timer := time.AfterFunc(time.Until(deadline), func() {
+		cmd.Process.Kill()
+	})
+	err := cmd.Wait()
+	if !timer.Stop() {
+		return out.Bytes(), errOutOfTime
+	}
+
+	return out.Bytes(), err
+}
+
+// stop records where the load stopped and returns errOutOfTime.
+func (c *objectCollector) stop(ref string, commit string) error {
+	if c.report.Stopped == nil {
+		c.report.Stopped = &StopPoint{Ref: ref, Commit: commit}
+	}
+
+	return errOutOfTime
+}
+
+// durationOption accepts durations as time.Duration, strings such as "30m",
+// or a number of seconds.
+func durationOption(v interface{}) (time.Duration, bool) {
+	switch d := v.(type) {
+	case time.Duration:
+		return d, true
+	case int:
+		return time.Duration(d) * time.Second, true
+	case string:
+		if n, err := strconv.Atoi(d); err == nil {
+			return time.Duration(n) * time.Second, true
+		}
+		if dur, err := time.ParseDuration(d); err == nil {
+			return dur, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,106 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// objectsFromConflicts emits one object per stage (base, ours, theirs) of
+// every conflicted index entry, and records the conflicted paths so the
+// regular staged-file collection can skip them.
+func objectsFromConflicts(repo *git.Repository, index *git.Index, conflicted map[string]bool, collector *objectCollector) ([]models.Object, error) {
+	var objectList []models.Object
+
+	iter, err := index.ConflictIterator()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Free()
+
+	for {
+		conflict, err := iter.Next()
+		if err != nil {
+			if isIterOver(err) {
+				break
+			}
+			return nil, err
+		}
+
+		stages := []struct {
+			name  string
+			entry *git.IndexEntry
+		}{
+			{"base", conflict.Ancestor},
+			{"ours", conflict.Our},
+			{"theirs", conflict.Their},
+		}
+
+		for _, stage := range stages {
+			if stage.entry == nil {
+				continue
+			}
+			conflicted[stage.entry.Path] = true
+
+			blob, err := repo.LookupBlob(stage.entry.Id)
+			if err != nil {
+				err = collector.corrupt(stage.entry.Id.String(), stage.entry.Path, "", err)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			o := models.NewObject(stage.entry.Path, Type, "file-content", blob.Contents())
+			blob.Free()
+			setUniqId(o, stage.entry.Id.String())
+			o.SetMetadata("status", "conflict", models.MetadataAttributes{})
+			o.SetMetadata("stage", stage.name, models.MetadataAttributes{})
+			objectList = append(objectList, *o)
+		}
+	}
+
+	return objectList, nil
+}
+
+// objectsFromMergeState emits the pending merge message (MERGE_MSG) of a
+// repository that is in the middle of a merge, revert or cherry-pick.
+func objectsFromMergeState(repo *git.Repository) ([]models.Object, error) {
+	var objectList []models.Object
+
+	msg, err := ioutil.ReadFile(filepath.Join(repo.Path(), "MERGE_MSG"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	o := models.NewObject("merge-message", Type, "commit-message", msg)
+	o.SetMetadata("status", "staged", models.MetadataAttributes{})
+	o.SetMetadata("repo-state", repositoryStateName(repo.State()), models.MetadataAttributes{})
+	objectList = append(objectList, *o)
+
+	return objectList, nil
+}
+
+func repositoryStateName(state git.RepositoryState) string {
+	switch state {
+	case git.RepositoryStateMerge:
+		return "merge"
+	case git.RepositoryStateRevert:
+		return "revert"
+	case git.RepositoryStateCherrypick:
+		return "cherry-pick"
+	case git.RepositoryStateBisect:
+		return "bisect"
+	case git.RepositoryStateRebase, git.RepositoryStateRebaseInteractive, git.RepositoryStateRebaseMerge:
+		return "rebase"
+	case git.RepositoryStateApplyMailbox, git.RepositoryStateApplyMailboxOrRebase:
+		return "am"
+	}
+
+	return "none"
+}
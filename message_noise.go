@@ -0,0 +1,86 @@
+package sourcegit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// mergeBoilerplate matches the messages git writes for merges, when nothing
+// was added to them.
+var mergeBoilerplate = regexp.MustCompile(`^Merge (branch|branches|remote-tracking branch|tag|commit) [^\n]*$`)
+
+// botNames are automation accounts without the "[bot]" suffix.
+var botNames = map[string]bool{
+	"renovate-bot": true,
+	"dependabot":   true,
+}
+
+// messageNoise recognizes the commit messages not worth scanning:
+// too short, written by bots, merge boilerplate, or matching
+// message-noise-patterns.
+type messageNoise struct {
+	minLength int
+	defaults  bool
+	patterns  []*regexp.Regexp
+}
+
+// newMessageNoise returns nil when no commit message is to be dropped.
+func newMessageNoise(opt SourceGitLoadOptions) (*messageNoise, error) {
+	if opt.MessageMinLength <= 0 && !opt.MessageNoise && len(opt.MessageNoisePatterns) == 0 {
+		return nil, nil
+	}
+
+	n := &messageNoise{
+		minLength: opt.MessageMinLength,
+		defaults:  opt.MessageNoise,
+	}
+	for _, pattern := range opt.MessageNoisePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message-noise-patterns entry %q: %v", pattern, err)
+		}
+		n.patterns = append(n.patterns, re)
+	}
+
+	return n, nil
+}
+
+// noise reports whether the message of commit is to be dropped.
+func (n *messageNoise) noise(commit *git.Commit) bool {
+	if n == nil {
+		return false
+	}
+
+	message := strings.TrimSpace(commit.Message())
+	if len(message) < n.minLength {
+		return true
+	}
+
+	if n.defaults {
+		if mergeBoilerplate.MatchString(message) || isBot(commit.Author()) {
+			return true
+		}
+	}
+
+	for _, re := range n.patterns {
+		if re.MatchString(message) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isBot reports whether sig is an automation account such as
+// dependabot[bot] or renovate[bot].
+func isBot(sig *git.Signature) bool {
+	if sig == nil {
+		return false
+	}
+
+	name := strings.ToLower(sig.Name)
+	return strings.HasSuffix(name, "[bot]") || strings.Contains(strings.ToLower(sig.Email), "[bot]@") || botNames[name]
+}
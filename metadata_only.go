@@ -0,0 +1,27 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+	"strconv"
+)
+
+// metadataOnlyObject returns the content-less object of a blob, reading only
+// its header to get the size.
+func metadataOnlyObject(repo *git.Repository, path string, id *git.Oid) (*models.Object, error) {
+	odb, err := repo.Odb()
+	if err != nil {
+		return nil, err
+	}
+	defer odb.Free()
+
+	size, _, err := odb.ReadHeader(id)
+	if err != nil {
+		return nil, err
+	}
+
+	o := models.NewObject(path, Type, "file-content", nil)
+	o.SetMetadata("size", strconv.FormatUint(size, 10), models.MetadataAttributes{})
+
+	return o, nil
+}
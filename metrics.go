@@ -0,0 +1,59 @@
+package sourcegit
+
+import (
+	"time"
+)
+
+// Names of the metrics reported to Metrics.
+const (
+	// Time spent cloning remote sources.
+	MetricCloneDuration = "clone_duration"
+	// Time spent in a whole load.
+	MetricLoadDuration = "load_duration"
+	// Commits visited by the history walk.
+	MetricCommitsWalked = "commits_walked"
+	// Content bytes read from the repository.
+	MetricBytesRead = "bytes_read"
+	// Objects returned, after the object filter.
+	MetricObjectsEmitted = "objects_emitted"
+)
+
+// Metrics receives counters and timings of the loads of a source, to be
+// wired to a monitoring system such as Prometheus. Implementations must be
+// safe for concurrent use when several loads run in parallel.
+type Metrics interface {
+	// Add increments the counter name by delta.
+	Add(name string, delta int64)
+	// Observe records a duration of the timer name.
+	Observe(name string, d time.Duration)
+}
+
+// SetMetrics installs the metrics receiver of the source, replacing any
+// previous one. A nil receiver disables metrics.
+func (s *SourceGit) SetMetrics(metrics Metrics) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.metrics = metrics
+}
+
+func (s *SourceGit) metricsReceiver() Metrics {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.metrics
+}
+
+// metricAdd increments a counter, if metrics are enabled.
+func (c *objectCollector) metricAdd(name string, delta int64) {
+	if c.metrics != nil && delta != 0 {
+		c.metrics.Add(name, delta)
+	}
+}
+
+// observeSince records the time elapsed since start, if metrics are enabled.
+func (c *objectCollector) observeSince(name string, start time.Time) {
+	if c.metrics != nil {
+		c.metrics.Observe(name, time.Since(start))
+	}
+}
@@ -0,0 +1,120 @@
+package sourcegit
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type netrcMachine struct {
+	Name     string
+	Login    string
+	Password string
+}
+
+// netrcPath returns the netrc file to read: $NETRC when set, ~/.netrc
+// otherwise.
+func netrcPath() string {
+	if p := os.Getenv("NETRC"); p != "" {
+		return p
+	}
+
+	return filepath.Join(os.Getenv("HOME"), ".netrc")
+}
+
+// parseNetrc reads the machine entries of a netrc file. The "default" entry,
+// if present, is returned with an empty Name. macdef bodies are skipped.
+func parseNetrc(path string) ([]netrcMachine, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var machines []netrcMachine
+	var current *netrcMachine
+	var inMacdef bool
+
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if inMacdef {
+			if strings.TrimSpace(line) == "" {
+				inMacdef = false
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			if strings.HasPrefix(fields[i], "#") {
+				break
+			}
+
+			switch fields[i] {
+			case "machine", "default":
+				machines = append(machines, netrcMachine{})
+				current = &machines[len(machines)-1]
+				if fields[i] == "machine" && i+1 < len(fields) {
+					i++
+					current.Name = fields[i]
+				}
+			case "login", "password", "account":
+				if i+1 >= len(fields) {
+					break
+				}
+				i++
+				if current == nil {
+					continue
+				}
+				if fields[i-1] == "login" {
+					current.Login = fields[i]
+				} else if fields[i-1] == "password" {
+					current.Password = fields[i]
+				}
+			case "macdef":
+				inMacdef = true
+				i = len(fields)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return machines, nil
+}
+
+// netrcCredentials looks up the login and password for host in the user's
+// netrc file, falling back to the "default" entry.
+func netrcCredentials(host string) (string, string, error) {
+	machines, err := parseNetrc(netrcPath())
+	if err != nil {
+		return "", "", err
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	var fallback *netrcMachine
+	for i, m := range machines {
+		if m.Name == host && m.Password != "" {
+			return m.Login, m.Password, nil
+		}
+		if m.Name == "" && fallback == nil {
+			fallback = &machines[i]
+		}
+	}
+
+	if fallback != nil && fallback.Password != "" {
+		return fallback.Login, fallback.Password, nil
+	}
+
+	return "", "", fmt.Errorf("no netrc entry for %s", host)
+}
@@ -0,0 +1,76 @@
+package sourcegit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseNetrc(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []netrcMachine
+	}{
+		{
+			"one line",
+			"machine example.com login user password secret\n",
+			[]netrcMachine{{Name: "example.com", Login: "user", Password: "secret"}},
+		},
+		{
+			"several lines",
+			"machine example.com\n  login user\n  password secret\nmachine other.com login other password pass\n",
+			[]netrcMachine{
+				{Name: "example.com", Login: "user", Password: "secret"},
+				{Name: "other.com", Login: "other", Password: "pass"},
+			},
+		},
+		{
+			"default",
+			"machine example.com login user password secret\ndefault login anonymous password guest\n",
+			[]netrcMachine{
+				{Name: "example.com", Login: "user", Password: "secret"},
+				{Login: "anonymous", Password: "guest"},
+			},
+		},
+		{
+			"account and comments",
+			"# comment\nmachine example.com login user account acct password secret # comment password other\n",
+			[]netrcMachine{{Name: "example.com", Login: "user", Password: "secret"}},
+		},
+		{
+			"macdef",
+			"macdef init\nmachine evil.com login evil password evil\n\nmachine example.com login user password secret\n",
+			[]netrcMachine{{Name: "example.com", Login: "user", Password: "secret"}},
+		},
+		{
+			"tokens before any machine",
+			"login user password secret\n",
+			nil,
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "sourcegit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range tests {
+		path := filepath.Join(dir, "netrc")
+		if err := ioutil.WriteFile(path, []byte(test.data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := parseNetrc(path)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
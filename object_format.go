@@ -0,0 +1,57 @@
+package sourcegit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apuigsech/seekret/models"
+)
+
+// Hash algorithms of repository objects. libgit2 only reads SHA-1
+// repositories: the history of local SHA-256 ones is read with the git CLI
+// instead (see objectsFromSha256), and SHA-256 bundles are refused.
+const (
+	objectFormatSha1   = "sha1"
+	objectFormatSha256 = "sha256"
+)
+
+// localObjectFormat returns the object format of the repository at path, as
+// told by the git CLI: "sha1" or "sha256".
+func localObjectFormat(path string) string {
+	out, err := gitCommand(path, "rev-parse", "--show-object-format").Output()
+	if err != nil {
+		// Versions of git older than 2.29 know nothing but SHA-1.
+		return objectFormatSha1
+	}
+
+	return strings.ToLower(strings.TrimSpace(string(out)))
+}
+
+// checkObjectFormat fails with ErrUnsupportedObjectFormat for the object
+// formats libgit2 cannot read, of the repository or bundle at source.
+func checkObjectFormat(source string, format string) error {
+	if format = strings.ToLower(format); format != "" && format != objectFormatSha1 {
+		return &SourceError{Kind: ErrUnsupportedObjectFormat, Source: source, Err: fmt.Errorf("%s objects cannot be read by libgit2", format)}
+	}
+
+	return nil
+}
+
+// setUniqId sets the "uniq-id" primary key of an object to the id of its
+// blob. SHA-256 ids, 64 characters long, are prefixed with "sha256:" so
+// keys from repositories of different formats never collide, and SHA-1 keys
+// stay the same as they have always been.
+func setUniqId(o *models.Object, id string) {
+	if len(id) == 64 {
+		id = objectFormatSha256 + ":" + id
+	}
+
+	o.SetMetadata("uniq-id", id, models.MetadataAttributes{
+		PrimaryKey: true,
+	})
+}
+
+// uniqIdOid returns the object id stored in a "uniq-id" value.
+func uniqIdOid(uniqId string) string {
+	return strings.TrimPrefix(uniqId, objectFormatSha256+":")
+}
@@ -0,0 +1,86 @@
+package sourcegit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apuigsech/seekret"
+	"github.com/apuigsech/seekret/models"
+)
+
+func TestSha256Repository(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sourcegit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testGit(t, dir, "init", "--quiet", "--object-format=sha256")
+	if err := ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte("password=hunter2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testGit(t, dir, "add", ".")
+	testGit(t, dir, "commit", "--quiet", "-m", "add secret")
+
+	s := &SourceGit{}
+	defer s.Close()
+
+	objects, report, err := s.LoadObjectsWithReport(dir, seekret.LoadOptions{
+		"commit-files":    true,
+		"commit-messages": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.ObjectFormat != objectFormatSha256 {
+		t.Errorf("object format %q, want %q", report.ObjectFormat, objectFormatSha256)
+	}
+
+	var file, message bool
+	for _, o := range objects {
+		switch o.SubType {
+		case "file-content":
+			file = true
+			if !strings.Contains(string(o.Content), "hunter2") {
+				t.Errorf("%s: content %q", o.Name, o.Content)
+			}
+			id, err := o.GetMetadata("uniq-id")
+			if err != nil || !strings.HasPrefix(id, "sha256:") || len(uniqIdOid(id)) != 64 {
+				t.Errorf("%s: uniq-id %q", o.Name, id)
+			}
+		case "commit-message":
+			message = true
+			if strings.TrimSpace(string(o.Content)) != "add secret" {
+				t.Errorf("commit message %q", o.Content)
+			}
+		}
+	}
+	if !file || !message {
+		t.Errorf("file content loaded: %v, commit message loaded: %v", file, message)
+	}
+}
+
+func TestUniqId(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{strings.Repeat("a", 40), strings.Repeat("a", 40)},
+		{strings.Repeat("b", 64), "sha256:" + strings.Repeat("b", 64)},
+	}
+
+	for _, test := range tests {
+		o := models.NewObject("file.txt", Type, "file-content", nil)
+		setUniqId(o, test.id)
+		got, _ := o.GetMetadata("uniq-id")
+		if got != test.want {
+			t.Errorf("setUniqId(%q) = %q, want %q", test.id, got, test.want)
+		}
+		if uniqIdOid(got) != test.id {
+			t.Errorf("uniqIdOid(%q) = %q, want %q", got, uniqIdOid(got), test.id)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package sourcegit
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+)
+
+// isPackSource reports whether source is a packfile with its index next to
+// it (foo.pack and foo.idx).
+func isPackSource(source string) bool {
+	if !strings.HasSuffix(source, ".pack") {
+		return false
+	}
+
+	for _, file := range []string{source, strings.TrimSuffix(source, ".pack") + ".idx"} {
+		if info, err := os.Stat(file); err != nil || info.IsDir() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// openGitRepoPack makes a captured .pack/.idx pair readable by copying it
+// into the object database of a temporary bare repository.
+func openGitRepoPack(path string) (*git.Repository, error) {
+	tmpdir, err := tempDir("pack", path)
+	if err != nil {
+		return nil, err
+	}
+
+	opened := false
+	defer func() {
+		if !opened {
+			removeTempDir(tmpdir)
+		}
+	}()
+
+	repo, err := git.InitRepository(tmpdir, true)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), ".pack")
+	if !strings.HasPrefix(name, "pack-") {
+		name = "pack-" + name
+	}
+
+	packDir := filepath.Join(repo.Path(), "objects", "pack")
+	for _, ext := range []string{".pack", ".idx"} {
+		src := strings.TrimSuffix(path, ".pack") + ext
+		if err := copyFile(src, filepath.Join(packDir, name+ext)); err != nil {
+			repo.Free()
+			return nil, err
+		}
+	}
+
+	opened = true
+
+	return repo, nil
+}
+
+// objectsFromPack enumerates the objects of a repository without refs, as
+// one opened from a bare pack: commit messages for every commit and file
+// contents for every blob. Blobs are named after the first path a commit of
+// the pack gives them, or after their id when no commit references them.
+func objectsFromPack(repo *git.Repository, opt SourceGitLoadOptions, collector *objectCollector) error {
+	odb, err := repo.Odb()
+	if err != nil {
+		return err
+	}
+	defer odb.Free()
+
+	var commits, blobs []*git.Oid
+	err = odb.ForEach(func(oid *git.Oid) error {
+		obj, err := repo.Lookup(oid)
+		if err != nil {
+			return collector.corrupt(oid.String(), "", "", err)
+		}
+		defer obj.Free()
+
+		switch obj.Type() {
+		case git.ObjectCommit:
+			commits = append(commits, oid)
+		case git.ObjectBlob:
+			blobs = append(blobs, oid)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	paths := make(map[string]string)
+	commitOf := make(map[string]string)
+
+	for _, oid := range commits {
+		commit, err := repo.LookupCommit(oid)
+		if err != nil {
+			if err := collector.corrupt(oid.String(), "", "", err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if opt.CommitMessages && !collector.messageNoise.noise(commit) {
+			if err := collector.add(objectsFromCommitMessage(commit, opt)...); err != nil {
+				commit.Free()
+				return err
+			}
+		}
+
+		// Trees may be partially or not at all in the pack.
+		if tree, err := commit.Tree(); err == nil {
+			git.WalkTree(tree, func(base string, entry *git.TreeEntry) int {
+				if entry.Type == git.ObjectBlob {
+					if _, ok := paths[entry.Id.String()]; !ok {
+						paths[entry.Id.String()] = base + entry.Name
+						commitOf[entry.Id.String()] = oid.String()
+					}
+				}
+				return 0
+			})
+			tree.Free()
+		}
+		commit.Free()
+	}
+
+	if !opt.CommitFiles {
+		return nil
+	}
+
+	for _, oid := range blobs {
+		blob, err := repo.LookupBlob(oid)
+		if err != nil {
+			if err := collector.corrupt(oid.String(), "", "", err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, ok := paths[oid.String()]
+		if !ok {
+			name = fmt.Sprintf("blob-%s", oid)
+		}
+
+		o := models.NewObject(name, Type, "file-content", blob.Contents())
+		blob.Free()
+
+		if commit, ok := commitOf[oid.String()]; ok {
+			o.SetMetadata("commit", commit, models.MetadataAttributes{})
+		}
+		setUniqId(o, oid.String())
+
+		if err := collector.add(*o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
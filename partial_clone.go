@@ -0,0 +1,300 @@
+package sourcegit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// partialViews maps the views of partial clones open (see openPartialClone)
+// to the git directory of their partial clone.
+var (
+	partialViewsMu sync.Mutex
+	partialViews   = make(map[string]string)
+)
+
+// promisorRemote is partialCloneRemote for the repository at path, read
+// with the git CLI, for repositories libgit2 cannot open.
+func promisorRemote(path string) string {
+	if out, err := gitCommand(path, "config", "--get", "extensions.partialclone").Output(); err == nil && strings.TrimSpace(string(out)) != "" {
+		return strings.TrimSpace(string(out))
+	}
+
+	out, _ := gitCommand(path, "config", "--bool", "--get-regexp", `^remote\..*\.promisor$`).Output()
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[1] == "true" {
+			return strings.TrimSuffix(strings.TrimPrefix(fields[0], "remote."), ".promisor")
+		}
+	}
+
+	return ""
+}
+
+// openPartialClone opens the partial clone at path, whose promisor remote is
+// remote. "git clone --filter" makes repositories in format 1, which libgit2
+// refuses to open, so a view of it in format 0 is opened instead, from a
+// temporary directory: it borrows the objects of the partial clone as
+// alternates, has a copy of its refs, HEAD, index and remotes, and its
+// working tree. The blobs fetched later into the partial clone are found
+// through the view too.
+func openPartialClone(path string, remote string) (*git.Repository, error) {
+	out, err := gitCommand(path, "rev-parse", "--absolute-git-dir", "--git-common-dir", "--is-bare-repository").Output()
+	if err != nil {
+		return nil, fmt.Errorf("reading partial clone %s: %v", path, err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 3 {
+		return nil, fmt.Errorf("reading partial clone %s: unexpected %q", path, out)
+	}
+	gitDir, commonDir, bare := lines[0], lines[1], lines[2] == "true"
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(path, commonDir)
+	}
+
+	refs, err := gitCommand(path, "for-each-ref", "--format=%(objectname) %(refname)").Output()
+	if err != nil {
+		return nil, fmt.Errorf("reading refs of partial clone %s: %v", path, err)
+	}
+
+	// HEAD is a symbolic ref, or detached.
+	head, err := gitCommand(path, "symbolic-ref", "-q", "HEAD").Output()
+	if err == nil {
+		head = append([]byte("ref: "), head...)
+	} else if head, err = gitCommand(path, "rev-parse", "-q", "--verify", "HEAD").Output(); err != nil {
+		return nil, fmt.Errorf("reading HEAD of partial clone %s: %v", path, err)
+	}
+
+	// Remotes, for fetch-refs and the like.
+	remotes, _ := gitCommand(path, "config", "--get-regexp", `^remote\.`).Output()
+
+	workdir := ""
+	if !bare {
+		out, err := gitCommand(path, "rev-parse", "--show-toplevel").Output()
+		if err != nil {
+			return nil, fmt.Errorf("reading partial clone %s: %v", path, err)
+		}
+		workdir = strings.TrimSpace(string(out))
+	}
+
+	view, err := tempDir("partial", path)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := openPartialView(view, gitDir, commonDir, remote, refs, head, remotes, workdir)
+	if err != nil {
+		removeTempDir(view)
+		return nil, err
+	}
+
+	partialViewsMu.Lock()
+	partialViews[repo.Path()] = commonDir
+	partialViewsMu.Unlock()
+
+	return repo, nil
+}
+
+// openPartialView creates the view of a partial clone in dir, and opens it.
+func openPartialView(dir, gitDir, commonDir, remote string, refs, head, remotes []byte, workdir string) (*git.Repository, error) {
+	repo, err := git.InitRepository(dir, true)
+	if err != nil {
+		return nil, err
+	}
+	repo.Free()
+
+	files := map[string][]byte{
+		filepath.Join(dir, "objects", "info", "alternates"): []byte(filepath.Join(commonDir, "objects") + "\n"),
+		filepath.Join(dir, "packed-refs"):                   refs,
+		filepath.Join(dir, "HEAD"):                          head,
+	}
+	if index, err := ioutil.ReadFile(filepath.Join(gitDir, "index")); err == nil {
+		files[filepath.Join(dir, "index")] = index
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(name, content, 0600); err != nil {
+			return nil, err
+		}
+	}
+
+	repo, err = git.OpenRepository(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// extensions.partialclone is ignored by libgit2 (and by git in format
+	// 0): it is where partialCloneRemote finds the promisor remote. The
+	// other remote settings are copied, but for those that would make a
+	// promisor remote of the view itself.
+	config, err := repo.Config()
+	if err == nil {
+		err = config.SetString("extensions.partialclone", remote)
+		for _, line := range strings.Split(string(remotes), "\n") {
+			fields := strings.SplitN(line, " ", 2)
+			if err != nil || len(fields) != 2 || strings.HasSuffix(fields[0], ".promisor") || strings.HasSuffix(fields[0], ".partialclonefilter") {
+				continue
+			}
+			err = config.SetMultivar(fields[0], "^$", fields[1])
+		}
+		config.Free()
+	}
+	if err == nil && workdir != "" {
+		err = repo.SetWorkdir(workdir, false)
+	}
+	if err != nil {
+		repo.Free()
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// partialCloneDir returns the git directory promised blobs are fetched
+// into: that of the partial clone when repo is a view of it, else that of
+// repo.
+func partialCloneDir(repo *git.Repository) string {
+	partialViewsMu.Lock()
+	defer partialViewsMu.Unlock()
+
+	if dir, ok := partialViews[repo.Path()]; ok {
+		return dir
+	}
+
+	return repo.Path()
+}
+
+// releasePartialView removes the view of a partial clone whose git
+// directory is path, once freed. Other repositories are left alone.
+func releasePartialView(path string) {
+	partialViewsMu.Lock()
+	_, ok := partialViews[path]
+	delete(partialViews, path)
+	partialViewsMu.Unlock()
+
+	if ok {
+		removeTempDir(path)
+	}
+}
+
+// partialCloneRemote returns the promisor remote of a repository created with
+// "git clone --filter", or an empty string for complete repositories.
+func partialCloneRemote(repo *git.Repository) string {
+	config, err := repo.Config()
+	if err != nil {
+		return ""
+	}
+	defer config.Free()
+
+	if remote, err := config.LookupString("extensions.partialclone"); err == nil && remote != "" {
+		return remote
+	}
+
+	iter, err := config.NewIteratorGlob(`remote\..*\.promisor`)
+	if err != nil {
+		return ""
+	}
+	defer iter.Free()
+
+	for {
+		entry, err := iter.Next()
+		if err != nil {
+			break
+		}
+		if entry.Value == "true" {
+			return strings.TrimSuffix(strings.TrimPrefix(entry.Name, "remote."), ".promisor")
+		}
+	}
+
+	return ""
+}
+
+// isPromised reports whether a failed blob lookup is a blob the promisor
+// remote of a partial clone has not sent yet.
+func (c *objectCollector) isPromised(err error) bool {
+	return c.partialCloneRemote != "" && isNotFound(err)
+}
+
+func (c *objectCollector) promise(id string) {
+	if c.promised == nil {
+		c.promised = make(map[string]bool)
+	}
+	c.promised[id] = true
+}
+
+// resolvePromised fetches the blobs promised during the walk, when
+// partial-clone is "fetch", and releases the objects held waiting for them.
+// Blobs that are skipped or still unavailable end up in the load report.
+func (c *objectCollector) resolvePromised(repo *git.Repository) error {
+	if len(c.promised) == 0 {
+		return nil
+	}
+
+	if c.opt.PartialClone == "fetch" {
+		ids := make([]string, 0, len(c.promised))
+		for id := range c.promised {
+			ids = append(ids, id)
+		}
+
+		batch := c.opt.PartialCloneBatch
+		if batch <= 0 {
+			batch = len(ids)
+		}
+
+		for start := 0; start < len(ids); start += batch {
+			// Batches left at max-duration are reported below.
+			if c.outOfTime() {
+				c.stop("", "")
+				break
+			}
+
+			end := start + batch
+			if end > len(ids) {
+				end = len(ids)
+			}
+
+			args := []string{"-c", "fetch.negotiationAlgorithm=noop", "-c", "remote." + c.partialCloneRemote + ".uploadpack=git-upload-pack", "fetch", "--quiet", "--no-tags", "--no-write-fetch-head", "--recurse-submodules=no", "--filter=blob:none", c.partialCloneRemote}
+			args = append(args, ids[start:end]...)
+			if out, err := outputUntil(gitCommand(c.partialCloneDir, args...), c.deadline); err != nil {
+				// Leave the batch unresolved, it is reported below.
+				c.report.Warnings = append(c.report.Warnings, "fetching promised objects: "+strings.TrimSpace(string(out)))
+			}
+		}
+	}
+
+	held := c.held
+	c.held = nil
+	c.promised = nil
+
+	reported := make(map[string]bool)
+	for _, o := range held {
+		id, _ := o.GetMetadata("uniq-id")
+		commit, _ := o.GetMetadata("commit")
+
+		if c.opt.PartialClone == "fetch" {
+			if oid, err := git.NewOid(id); err == nil {
+				if blob, err := repo.LookupBlob(oid); err == nil {
+					o.Content = blob.Contents()
+					blob.Free()
+					if err := c.add(o); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+		}
+
+		if !reported[id] {
+			reported[id] = true
+			c.report.Promised = append(c.report.Promised, PromisedObject{
+				Id:     id,
+				Path:   o.Name,
+				Commit: commit,
+			})
+		}
+	}
+
+	return nil
+}
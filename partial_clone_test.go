@@ -0,0 +1,77 @@
+package sourcegit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apuigsech/seekret"
+)
+
+// testPartialClone returns a blob-less clone of a repository holding a
+// secret.
+func testPartialClone(t *testing.T) string {
+	t.Helper()
+
+	src := testRepo(t, "one\n", "password=hunter2\n")
+	testGit(t, src, "config", "uploadpack.allowfilter", "true")
+
+	dir, err := ioutil.TempDir("", "sourcegit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	testGit(t, dir, "clone", "--quiet", "--filter=blob:none", "--no-checkout", "file://"+src, "partial")
+
+	return filepath.Join(dir, "partial")
+}
+
+func TestPartialCloneSkip(t *testing.T) {
+	s := &SourceGit{}
+	defer s.Close()
+
+	objects, report, err := s.LoadObjectsWithReport(testPartialClone(t), seekret.LoadOptions{
+		"commit-files":  true,
+		"partial-clone": "skip",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Promised) == 0 {
+		t.Errorf("no promised blob reported")
+	}
+	for _, o := range objects {
+		if strings.Contains(string(o.Content), "hunter2") {
+			t.Errorf("%s: content of a promised blob loaded without fetching it", o.Name)
+		}
+	}
+}
+
+func TestPartialCloneFetch(t *testing.T) {
+	s := &SourceGit{}
+	defer s.Close()
+
+	objects, report, err := s.LoadObjectsWithReport(testPartialClone(t), seekret.LoadOptions{
+		"commit-files":  true,
+		"partial-clone": "fetch",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Promised) != 0 {
+		t.Errorf("promised blobs left unfetched: %v", report.Promised)
+	}
+
+	found := false
+	for _, o := range objects {
+		if strings.Contains(string(o.Content), "hunter2") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("fetched blob not loaded")
+	}
+}
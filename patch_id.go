@@ -0,0 +1,74 @@
+package sourcegit
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+	"unicode"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// patchId computes an id of the change introduced by commit that does not
+// depend on where it was applied, in the spirit of "git patch-id --stable":
+// paths and added/removed lines with whitespace removed, ignoring line
+// numbers and context. Merge commits and commits without changes have no
+// patch id.
+func patchId(repo *git.Repository, commit *git.Commit) (string, error) {
+	if commit.ParentCount() > 1 {
+		return "", nil
+	}
+
+	diff, err := diffFirstParent(repo, commit, nil)
+	if err != nil {
+		return "", err
+	}
+	defer diff.Free()
+
+	h := sha1.New()
+	changed := false
+
+	err = diff.ForEach(func(delta git.DiffDelta, progress float64) (git.DiffForEachHunkCallback, error) {
+		changed = true
+		fmt.Fprintf(h, "diff %s %s\n", delta.OldFile.Path, delta.NewFile.Path)
+		if delta.Flags&git.DiffFlagBinary != 0 {
+			fmt.Fprintf(h, "binary %s %s\n", delta.OldFile.Oid, delta.NewFile.Oid)
+		}
+
+		return func(hunk git.DiffHunk) (git.DiffForEachLineCallback, error) {
+			return func(line git.DiffLine) error {
+				switch line.Origin {
+				case git.DiffLineAddition:
+					writePatchLine(h, '+', line.Content)
+				case git.DiffLineDeletion:
+					writePatchLine(h, '-', line.Content)
+				}
+				return nil
+			}, nil
+		}, nil
+	}, git.DiffDetailLines)
+	if err != nil {
+		return "", err
+	}
+
+	if !changed {
+		return "", nil
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writePatchLine(h hash.Hash, origin byte, content string) {
+	stripped := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, content)
+
+	h.Write([]byte{origin})
+	h.Write([]byte(stripped))
+	h.Write([]byte{'\n'})
+}
@@ -0,0 +1,26 @@
+package sourcegit
+
+import (
+	"strings"
+)
+
+// pathDepth returns the number of components of p: 1 for files at the top
+// of the tree.
+func pathDepth(p string) int {
+	return strings.Count(strings.Trim(p, "/"), "/") + 1
+}
+
+// tooDeep reports whether p is below max-path-depth. For a directory, it
+// reports whether every file in it is.
+func (c *objectCollector) tooDeep(p string, dir bool) bool {
+	if c.opt.MaxPathDepth <= 0 {
+		return false
+	}
+
+	depth := pathDepth(p)
+	if dir {
+		depth++
+	}
+
+	return depth > c.opt.MaxPathDepth
+}
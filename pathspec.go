@@ -0,0 +1,64 @@
+package sourcegit
+
+import (
+	"path"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// commitTouchesPathspec reports whether commit changes any path matched by
+// pathspec relative to its first parent, like "git log -- <pathspec>".
+func commitTouchesPathspec(repo *git.Repository, commit *git.Commit, pathspec []string) (bool, error) {
+	diffOpts, err := git.DefaultDiffOptions()
+	if err != nil {
+		return false, err
+	}
+	diffOpts.Pathspec = pathspec
+
+	diff, err := diffFirstParent(repo, commit, &diffOpts)
+	if err != nil {
+		return false, err
+	}
+	defer diff.Free()
+
+	count, err := diff.NumDeltas()
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// matchPathspec reports whether p is matched by any of the specs, either as
+// the path itself, a leading directory, or a glob.
+func matchPathspec(p string, specs []string) bool {
+	for _, spec := range specs {
+		spec = strings.Trim(spec, "/")
+		if spec == "" || p == spec || strings.HasPrefix(p, spec+"/") {
+			return true
+		}
+		if ok, _ := path.Match(spec, p); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pathspecMayMatchDir reports whether any path below dir can be matched by
+// specs, so tree walks can skip unrelated subtrees.
+func pathspecMayMatchDir(dir string, specs []string) bool {
+	dir = strings.Trim(dir, "/")
+	for _, spec := range specs {
+		spec = strings.Trim(spec, "/")
+		if strings.ContainsAny(spec, "*?[") {
+			return true
+		}
+		if spec == "" || dir == spec || strings.HasPrefix(dir, spec+"/") || strings.HasPrefix(spec, dir+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,73 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret"
+)
+
+// profiles are the presets selectable with the "profile" load option. Options
+// set explicitly always take precedence over the ones of the profile.
+var profiles = map[string]seekret.LoadOptions{
+	// Recent history of the checked out branch only.
+	"quick": {
+		"commit-files":        true,
+		"commit-messages":     true,
+		"commit-count":        50,
+		"incremental-content": true,
+	},
+	// Every commit of the default branch, strict about repository damage and
+	// failing rather than holding more than 512MB of content (spooling to
+	// disk would make LoadObjects fail anyway).
+	"ci": {
+		"commit-files":        true,
+		"commit-messages":     true,
+		"default-branch-only": true,
+		"incremental-content": true,
+		"fail-on-corruption":  true,
+		"memory-limit":        "512MB",
+		"memory-limit-action": "abort",
+	},
+	// Everything the repository still holds, on every ref, with the
+	// metadata needed to tell when a secret was introduced and removed.
+	"deep-forensic": {
+		"commit-files":          true,
+		"commit-messages":       true,
+		"split-commit-messages": true,
+		"commit-metadata":       true,
+		"deleted-files":         true,
+		"staged-files":          true,
+		"all-branches":          true,
+		"ignore-grafts":         true,
+		"partial-clone":         "fetch",
+		"blob-lifetime":         true,
+		"sort":                  "topo",
+	},
+	// What is about to be committed.
+	"pre-commit": {
+		"staged-files": true,
+	},
+}
+
+// ApplyProfile returns the load options with the ones of the selected
+// profile filled in underneath, as the load sees them. Loads apply it
+// themselves: it is for callers that check the options of a load before
+// running it.
+func ApplyProfile(o seekret.LoadOptions) seekret.LoadOptions {
+	name, ok := o["profile"].(string)
+	if !ok {
+		return o
+	}
+	preset, ok := profiles[name]
+	if !ok {
+		return o
+	}
+
+	merged := make(seekret.LoadOptions, len(preset)+len(o))
+	for k, v := range preset {
+		merged[k] = v
+	}
+	for k, v := range o {
+		merged[k] = v
+	}
+
+	return merged
+}
@@ -0,0 +1,79 @@
+package sourcegit
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+	"strconv"
+)
+
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+type githubPullRequest struct {
+	Number  int        `json:"number"`
+	Title   string     `json:"title"`
+	Body    string     `json:"body"`
+	HtmlUrl string     `json:"html_url"`
+	User    githubUser `json:"user"`
+}
+
+type githubReviewComment struct {
+	Id             int64      `json:"id"`
+	Body           string     `json:"body"`
+	Path           string     `json:"path"`
+	CommitId       string     `json:"commit_id"`
+	HtmlUrl        string     `json:"html_url"`
+	PullRequestUrl string     `json:"pull_request_url"`
+	User           githubUser `json:"user"`
+}
+
+// loadPullRequests emits the title and description of every pull request of
+// the GitHub repository ("pr-description" objects) and the review comments
+// left on their diffs ("pr-review-comment" objects).
+func loadPullRequests(repo *git.Repository, opt SourceGitLoadOptions, collector *objectCollector) error {
+	if !opt.PullRequests {
+		return nil
+	}
+
+	name, err := githubRepository(repo)
+	if err != nil {
+		return err
+	}
+	client := newGithubClient(opt, collector.report)
+
+	err = client.each(fmt.Sprintf("/repos/%s/pulls?state=all", name), func(item json.RawMessage) error {
+		var pr githubPullRequest
+		if err := json.Unmarshal(item, &pr); err != nil {
+			return err
+		}
+
+		o := models.NewObject(fmt.Sprintf("pull/%d", pr.Number), Type, "pr-description", []byte(pr.Title+"\n\n"+pr.Body))
+		o.SetMetadata("pull-request", strconv.Itoa(pr.Number), models.MetadataAttributes{})
+		o.SetMetadata("url", pr.HtmlUrl, models.MetadataAttributes{})
+		o.SetMetadata("author", pr.User.Login, models.MetadataAttributes{})
+
+		return collector.add(*o)
+	})
+	if err != nil {
+		return err
+	}
+
+	return client.each(fmt.Sprintf("/repos/%s/pulls/comments", name), func(item json.RawMessage) error {
+		var comment githubReviewComment
+		if err := json.Unmarshal(item, &comment); err != nil {
+			return err
+		}
+
+		o := models.NewObject(fmt.Sprintf("pull-comment/%d", comment.Id), Type, "pr-review-comment", []byte(comment.Body))
+		o.SetMetadata("url", comment.HtmlUrl, models.MetadataAttributes{})
+		o.SetMetadata("author", comment.User.Login, models.MetadataAttributes{})
+		o.SetMetadata("path", comment.Path, models.MetadataAttributes{})
+		o.SetMetadata("commit", comment.CommitId, models.MetadataAttributes{})
+		o.SetMetadata("pull-request-url", comment.PullRequestUrl, models.MetadataAttributes{})
+
+		return collector.add(*o)
+	})
+}
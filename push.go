@@ -0,0 +1,121 @@
+package sourcegit
+
+import (
+	"fmt"
+	"github.com/apuigsech/seekret"
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+	"strings"
+)
+
+// EvaluatePush returns the objects a push updating ref from oldRev to newRev
+// would introduce, for update and pre-receive hooks to gate the push on:
+//
+//	objects, err := sourcegit.SourceTypeGit.EvaluatePush(os.Args[2], os.Args[3], os.Args[1])
+//
+// The repository is the one the hook runs in (GIT_DIR), quarantined objects
+// of the push included. Commit files and messages of the commits not
+// reachable from the old value of ref are returned, or, for a new ref, of
+// the commits not reachable from any existing ref. Deletions return nothing.
+func (s *SourceGit) EvaluatePush(oldRev, newRev, ref string) ([]models.Object, error) {
+	if newRev == zeroCommit {
+		return nil, nil
+	}
+
+	return s.LoadObjects(".", seekret.LoadOptions{
+		"commit-files":    true,
+		"commit-messages": true,
+		"push-update":     fmt.Sprintf("%s %s %s", oldRev, newRev, ref),
+	})
+}
+
+// pushUpdateRefs returns the scan ref of a push-update ("<old> <new> <ref>"
+// as given to an update hook): the commits reachable from the new value and
+// not from the old one, or from any other ref when the ref is created. An old
+// value missing from the repository, as in clones made after a forced
+// update, is handled like a created ref.
+func pushUpdateRefs(repo *git.Repository, spec string) ([]scanRef, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("push-update %q: expected \"<old> <new> <ref>\"", spec)
+	}
+
+	if fields[1] == zeroCommit {
+		return nil, nil
+	}
+
+	target, err := peelCommitId(repo, fields[1])
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		// Tags of trees and blobs bring no commit.
+		return nil, nil
+	}
+
+	if fields[0] != zeroCommit {
+		old, err := peelCommitId(repo, fields[0])
+		if err == nil {
+			var hide []*git.Oid
+			if old != nil {
+				hide = []*git.Oid{old}
+			}
+			return []scanRef{{Name: fields[2], Target: target, Hide: hide}}, nil
+		}
+		if !isNotFound(err) {
+			return nil, err
+		}
+	}
+
+	hide, err := otherRefTips(repo, fields[2])
+	if err != nil {
+		return nil, err
+	}
+
+	return []scanRef{{Name: fields[2], Target: target, Hide: hide}}, nil
+}
+
+// otherRefTips returns the tips of every ref but ref, which in clones is also
+// the remote-tracking ref of origin for a branch. Hooks run before ref
+// exists, clones of a webhook push after it does.
+func otherRefTips(repo *git.Repository, ref string) ([]*git.Oid, error) {
+	tips, err := refTipsByName(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var hide []*git.Oid
+	for name, tip := range tips {
+		if name == ref || strings.HasPrefix(ref, "refs/heads/") && name == "refs/remotes/origin/"+strings.TrimPrefix(ref, "refs/heads/") {
+			continue
+		}
+		if oid, err := git.NewOid(tip); err == nil {
+			hide = append(hide, oid)
+		}
+	}
+
+	return hide, nil
+}
+
+// peelCommitId returns the commit rev (a commit or a tag) points to, or nil
+// when it does not point to a commit.
+func peelCommitId(repo *git.Repository, rev string) (*git.Oid, error) {
+	id, err := git.NewOid(rev)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := repo.Lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Free()
+
+	commit, err := obj.Peel(git.ObjectCommit)
+	if err != nil {
+		return nil, nil
+	}
+	defer commit.Free()
+
+	return commit.Id(), nil
+}
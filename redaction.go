@@ -0,0 +1,94 @@
+package sourcegit
+
+import (
+	"bytes"
+	"regexp"
+	"unicode"
+
+	"github.com/apuigsech/seekret/models"
+)
+
+// Redactor rewrites the content of an object before it leaves the loader,
+// for deployments where raw repository content must not: every content goes
+// through it before the object filter sees it, including the contents read
+// later through LazyObjects. It returns the content to emit, and must not
+// modify content in place.
+type Redactor func(content []byte) []byte
+
+// SetRedactor installs the redactor of the source, replacing any previous
+// one. A nil redactor emits contents unchanged. It is shared by every scan
+// running on the source, so it must be safe for concurrent use.
+func (s *SourceGit) SetRedactor(redactor Redactor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.redactor = redactor
+}
+
+func (s *SourceGit) contentRedactor() Redactor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.redactor
+}
+
+// ChainRedactors returns a Redactor applying redactors in turn.
+func ChainRedactors(redactors ...Redactor) Redactor {
+	return func(content []byte) []byte {
+		for _, r := range redactors {
+			content = r(content)
+		}
+		return content
+	}
+}
+
+// Candidate tokens: runs of at least 16 characters of the alphabets of API
+// keys, base64 and hex, mixing letters and digits.
+var tokenRegexp = regexp.MustCompile(`[A-Za-z0-9_\-+/=.]{16,}`)
+
+// MaskTokens returns a Redactor replacing every character of the candidate
+// tokens of a content, but their last keep ones, with "*". Line and column
+// numbers of findings are kept.
+func MaskTokens(keep int) Redactor {
+	return func(content []byte) []byte {
+		masked := content
+		for _, loc := range tokenRegexp.FindAllIndex(content, -1) {
+			token := content[loc[0]:loc[1]]
+			if !bytes.ContainsAny(token, "0123456789") || bytes.IndexFunc(token, unicode.IsLetter) < 0 {
+				continue
+			}
+			if &masked[0] == &content[0] {
+				masked = append([]byte(nil), content...)
+			}
+			for i := loc[0]; i < loc[1]-keep; i++ {
+				masked[i] = '*'
+			}
+		}
+		return masked
+	}
+}
+
+// Personal data: email addresses, and phone numbers written in
+// international format.
+var (
+	emailRegexp = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phoneRegexp = regexp.MustCompile(`\+[0-9][0-9 .\-]{7,}[0-9]`)
+)
+
+// StripPersonalData returns a Redactor replacing email addresses and phone
+// numbers with "[redacted]".
+func StripPersonalData() Redactor {
+	return func(content []byte) []byte {
+		content = emailRegexp.ReplaceAll(content, []byte("[redacted]"))
+		return phoneRegexp.ReplaceAll(content, []byte("[redacted]"))
+	}
+}
+
+// redact applies the redactor of the load to the content of o.
+func (c *objectCollector) redact(o *models.Object) {
+	if c.redactor == nil || o.Content == nil {
+		return
+	}
+
+	o.Content = c.redactor(o.Content)
+}
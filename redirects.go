@@ -0,0 +1,18 @@
+package sourcegit
+
+import (
+	"strings"
+)
+
+// strictRedirectOptions returns the options for the git CLI to refuse every
+// redirect of an HTTP(S) remote with strict-redirects, or nothing. The
+// transport of the clone itself stops at the first redirect, so no
+// credentials are ever offered to where it points, whatever the remote
+// answers from one request to the next.
+func strictRedirectOptions(gitUri string, opt SourceGitLoadOptions) []string {
+	if !opt.StrictRedirects || !strings.HasPrefix(gitUri, "http://") && !strings.HasPrefix(gitUri, "https://") {
+		return nil
+	}
+
+	return []string{"-c", "http.followRedirects=false"}
+}
@@ -0,0 +1,94 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+type budgetedCommit struct {
+	id    *git.Oid
+	depth int
+}
+
+// walkRefsBudgeted walks refs with ref-commit-budget and round-robin-refs.
+// The commits of every ref are listed first, which only reads commits,
+// keeping at most ref-commit-budget of those not already listed for a
+// previous ref. They are then scanned ref after ref or, with
+// round-robin-refs, one commit of each ref in turn, so that an enormous
+// branch cannot hold back the others and a scan cut short still covers every
+// ref. Refs with commits left out are listed in the load report.
+func walkRefsBudgeted(repo *git.Repository, walker *historyWalker, refs []scanRef, opt SourceGitLoadOptions, collector *objectCollector, scan func(ref scanRef, commit *git.Commit, depth int) error) error {
+	queues := make([][]budgetedCommit, len(refs))
+	listed := make(map[string]bool)
+
+	for i, ref := range refs {
+		count := 0
+		stopped := false
+		err := walker.walk(ref, func(commit *git.Commit) bool {
+			if opt.CommitCount > 0 && count >= opt.CommitCount {
+				return false
+			}
+			if collector.outOfTime() {
+				stopped = true
+				return false
+			}
+			count++
+			collector.metricAdd(MetricCommitsWalked, 1)
+
+			if listed[commit.Id().String()] {
+				return true
+			}
+
+			if opt.RefCommitBudget > 0 && len(queues[i]) >= opt.RefCommitBudget {
+				collector.report.RefsOverBudget = append(collector.report.RefsOverBudget, ref.Name)
+				return false
+			}
+
+			listed[commit.Id().String()] = true
+			queues[i] = append(queues[i], budgetedCommit{id: commit.Id(), depth: walker.depth(commit)})
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		if stopped {
+			return collector.stop(ref.Name, "")
+		}
+	}
+
+	scanOne := func(i int, c budgetedCommit) error {
+		commit, err := repo.LookupCommit(c.id)
+		if err != nil {
+			return err
+		}
+		defer commit.Free()
+
+		return scan(refs[i], commit, c.depth)
+	}
+
+	if !opt.RoundRobinRefs {
+		for i := range queues {
+			for _, c := range queues[i] {
+				if err := scanOne(i, c); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for round := 0; ; round++ {
+		left := false
+		for i := range queues {
+			if round >= len(queues[i]) {
+				continue
+			}
+			left = true
+			if err := scanOne(i, queues[i][round]); err != nil {
+				return err
+			}
+		}
+		if !left {
+			return nil
+		}
+	}
+}
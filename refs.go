@@ -0,0 +1,225 @@
+package sourcegit
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+)
+
+// scanRef is a starting point of the history walk.
+type scanRef struct {
+	Name   string
+	Target *git.Oid
+	// Commits reachable from these are not walked.
+	Hide []*git.Oid
+	// Full name of the fork the ref comes from, in forks mode.
+	Fork string
+
+	// Only filled for all-branches scans.
+	LastCommit time.Time
+	Ahead      int
+	Behind     int
+}
+
+// collectRefs returns the refs the history walk starts from: HEAD, every
+// local and remote-tracking branch when all-branches is set, or the refs
+// matching the "refs" globs. The default branch always comes first so that
+// shared history is attributed to it. In fetch-refs mode, the walk starts
+// from what was just fetched instead, in forks mode, from the branches of
+// the forks, with revision-range, from the end of the range, and with
+// push-update, from the new value of the pushed ref.
+func collectRefs(repo *git.Repository, opt SourceGitLoadOptions, report *LoadReport) ([]scanRef, error) {
+	if len(opt.FetchRefs) > 0 {
+		return fetchRefs(repo, opt)
+	}
+
+	if opt.Forks {
+		return forkRefs(repo, opt, report)
+	}
+
+	if opt.RevisionRange != "" {
+		return revisionRangeRefs(repo, opt.RevisionRange)
+	}
+
+	if opt.PushUpdate != "" {
+		return pushUpdateRefs(repo, opt.PushUpdate)
+	}
+
+	def, err := defaultBranch(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer def.Free()
+
+	if opt.DefaultBranchOnly {
+		return []scanRef{{Name: def.Name(), Target: def.Target()}}, nil
+	}
+
+	if !opt.multiRef() {
+		head, err := repo.Head()
+		if err != nil {
+			return nil, err
+		}
+		defer head.Free()
+
+		return []scanRef{{Name: "HEAD", Target: head.Target()}}, nil
+	}
+
+	iter, err := repo.NewReferenceIterator()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Free()
+
+	var refs []scanRef
+	for {
+		ref, err := iter.Next()
+		if err != nil {
+			if isIterOver(err) {
+				break
+			}
+			return nil, err
+		}
+
+		if ref.Type() == git.ReferenceOid && selectRef(ref, opt) {
+			// Tags may point to tag objects, or to something else than a
+			// commit.
+			if obj, err := ref.Peel(git.ObjectCommit); err == nil {
+				refs = append(refs, scanRef{Name: ref.Name(), Target: obj.Id()})
+				obj.Free()
+			}
+		}
+		ref.Free()
+	}
+
+	for i := range refs {
+		if err := fillRefStaleness(repo, &refs[i], def.Target()); err != nil {
+			return nil, err
+		}
+	}
+
+	defaultName := def.Name()
+	sort.SliceStable(refs, func(i, j int) bool {
+		if (refs[i].Name == defaultName) != (refs[j].Name == defaultName) {
+			return refs[i].Name == defaultName
+		}
+		return refs[i].Name < refs[j].Name
+	})
+
+	return refs, nil
+}
+
+// defaultBranch returns the default branch of the repository: the branch the
+// origin remote's HEAD points to (which for clones is also the branch HEAD
+// was set to), or the branch checked out otherwise.
+func defaultBranch(repo *git.Repository) (*git.Reference, error) {
+	if remoteHead, err := repo.References.Lookup("refs/remotes/origin/HEAD"); err == nil {
+		ref, err := remoteHead.Resolve()
+		remoteHead.Free()
+		if err == nil {
+			return ref, nil
+		}
+	}
+
+	return repo.Head()
+}
+
+func (opt SourceGitLoadOptions) multiRef() bool {
+	return !opt.HeadOnly && (opt.AllBranches || len(opt.Refs) > 0)
+}
+
+// selectRef reports whether ref is one of the refs to walk.
+func selectRef(ref *git.Reference, opt SourceGitLoadOptions) bool {
+	if matchRefGlobs(ref.Name(), opt.ExcludeRefs) {
+		return false
+	}
+
+	if len(opt.Refs) == 0 {
+		return ref.IsBranch() || ref.IsRemote()
+	}
+
+	return matchRefGlobs(ref.Name(), opt.Refs)
+}
+
+// matchRefGlobs matches a ref name against globs such as
+// "refs/heads/release/*" or "refs/tags/v*". Like git for-each-ref, a pattern
+// without glob characters matches every ref below it. Remote-tracking
+// branches are matched as if they were local branches too, so
+// "refs/heads/release/*" also selects "refs/remotes/origin/release/1.0".
+func matchRefGlobs(name string, globs []string) bool {
+	candidates := []string{name}
+	if strings.HasPrefix(name, "refs/remotes/") {
+		parts := strings.SplitN(strings.TrimPrefix(name, "refs/remotes/"), "/", 2)
+		if len(parts) == 2 {
+			candidates = append(candidates, "refs/heads/"+parts[1])
+		}
+	}
+
+	for _, glob := range globs {
+		for _, candidate := range candidates {
+			if !strings.ContainsAny(glob, "*?[") {
+				prefix := strings.TrimSuffix(glob, "/")
+				if candidate == prefix || strings.HasPrefix(candidate, prefix+"/") {
+					return true
+				}
+				continue
+			}
+			if ok, _ := path.Match(glob, candidate); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// fillRefStaleness sets the last commit date of ref and how many commits it
+// is ahead of and behind the default branch.
+func fillRefStaleness(repo *git.Repository, ref *scanRef, defaultTarget *git.Oid) error {
+	commit, err := repo.LookupCommit(ref.Target)
+	if err != nil {
+		return err
+	}
+	ref.LastCommit = commit.Committer().When
+	commit.Free()
+
+	ahead, behind, err := repo.AheadBehind(ref.Target, defaultTarget)
+	if err != nil {
+		return err
+	}
+	ref.Ahead = ahead
+	ref.Behind = behind
+
+	return nil
+}
+
+// setRefMetadata attaches the branch an object was reached from, with its
+// staleness, so findings on abandoned branches can be triaged apart.
+func setRefMetadata(o *models.Object, ref scanRef) {
+	o.SetMetadata("branch", ref.Name, models.MetadataAttributes{})
+	o.SetMetadata("branch-last-commit", ref.LastCommit.UTC().Format(time.RFC3339), models.MetadataAttributes{})
+	o.SetMetadata("branch-ahead", strconv.Itoa(ref.Ahead), models.MetadataAttributes{})
+	o.SetMetadata("branch-behind", strconv.Itoa(ref.Behind), models.MetadataAttributes{})
+}
+
+// hideCommits adds the commits of exclude-commits to the hidden commits of
+// every ref.
+func hideCommits(refs []scanRef, ids []string) error {
+	for _, id := range ids {
+		oid, err := git.NewOid(id)
+		if err != nil {
+			return fmt.Errorf("exclude-commits %q: %v", id, err)
+		}
+		for i := range refs {
+			refs[i].Hide = append(refs[i].Hide, oid)
+		}
+	}
+
+	return nil
+}
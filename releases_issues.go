@@ -0,0 +1,112 @@
+package sourcegit
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+	"strconv"
+)
+
+type githubRelease struct {
+	Id      int64      `json:"id"`
+	TagName string     `json:"tag_name"`
+	Name    string     `json:"name"`
+	Body    string     `json:"body"`
+	HtmlUrl string     `json:"html_url"`
+	Author  githubUser `json:"author"`
+}
+
+type githubIssue struct {
+	Number      int              `json:"number"`
+	Title       string           `json:"title"`
+	Body        string           `json:"body"`
+	HtmlUrl     string           `json:"html_url"`
+	User        githubUser       `json:"user"`
+	PullRequest *json.RawMessage `json:"pull_request"`
+}
+
+type githubIssueComment struct {
+	Id       int64      `json:"id"`
+	Body     string     `json:"body"`
+	HtmlUrl  string     `json:"html_url"`
+	IssueUrl string     `json:"issue_url"`
+	User     githubUser `json:"user"`
+}
+
+// loadReleases emits the notes of every release of the GitHub repository
+// ("release-notes" objects).
+func loadReleases(repo *git.Repository, opt SourceGitLoadOptions, collector *objectCollector) error {
+	if !opt.Releases {
+		return nil
+	}
+
+	name, err := githubRepository(repo)
+	if err != nil {
+		return err
+	}
+
+	return newGithubClient(opt, collector.report).each(fmt.Sprintf("/repos/%s/releases", name), func(item json.RawMessage) error {
+		var release githubRelease
+		if err := json.Unmarshal(item, &release); err != nil {
+			return err
+		}
+
+		o := models.NewObject(fmt.Sprintf("release/%s", release.TagName), Type, "release-notes", []byte(release.Name+"\n\n"+release.Body))
+		o.SetMetadata("tag", release.TagName, models.MetadataAttributes{})
+		o.SetMetadata("url", release.HtmlUrl, models.MetadataAttributes{})
+		o.SetMetadata("author", release.Author.Login, models.MetadataAttributes{})
+
+		return collector.add(*o)
+	})
+}
+
+// loadIssues emits the title and description of every issue of the GitHub
+// repository ("issue" objects) and the comments on issues and pull requests
+// ("issue-comment" objects).
+func loadIssues(repo *git.Repository, opt SourceGitLoadOptions, collector *objectCollector) error {
+	if !opt.Issues {
+		return nil
+	}
+
+	name, err := githubRepository(repo)
+	if err != nil {
+		return err
+	}
+	client := newGithubClient(opt, collector.report)
+
+	err = client.each(fmt.Sprintf("/repos/%s/issues?state=all", name), func(item json.RawMessage) error {
+		var issue githubIssue
+		if err := json.Unmarshal(item, &issue); err != nil {
+			return err
+		}
+		// Pull requests are listed as issues too.
+		if issue.PullRequest != nil {
+			return nil
+		}
+
+		o := models.NewObject(fmt.Sprintf("issue/%d", issue.Number), Type, "issue", []byte(issue.Title+"\n\n"+issue.Body))
+		o.SetMetadata("issue", strconv.Itoa(issue.Number), models.MetadataAttributes{})
+		o.SetMetadata("url", issue.HtmlUrl, models.MetadataAttributes{})
+		o.SetMetadata("author", issue.User.Login, models.MetadataAttributes{})
+
+		return collector.add(*o)
+	})
+	if err != nil {
+		return err
+	}
+
+	return client.each(fmt.Sprintf("/repos/%s/issues/comments", name), func(item json.RawMessage) error {
+		var comment githubIssueComment
+		if err := json.Unmarshal(item, &comment); err != nil {
+			return err
+		}
+
+		o := models.NewObject(fmt.Sprintf("issue-comment/%d", comment.Id), Type, "issue-comment", []byte(comment.Body))
+		o.SetMetadata("url", comment.HtmlUrl, models.MetadataAttributes{})
+		o.SetMetadata("author", comment.User.Login, models.MetadataAttributes{})
+		o.SetMetadata("issue-url", comment.IssueUrl, models.MetadataAttributes{})
+
+		return collector.add(*o)
+	})
+}
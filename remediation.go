@@ -0,0 +1,153 @@
+package sourcegit
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// FindingLocation identifies an object reported by a scan: the path of the
+// file and the commit it was found in. Blob may be left empty, in which case
+// it is resolved from the repository.
+type FindingLocation struct {
+	Path   string
+	Commit string
+	Blob   string
+}
+
+// RemediationLists holds what history rewriting tools need to purge a set of
+// findings from a repository.
+type RemediationLists struct {
+	// Paths of the affected files, sorted and without duplicates.
+	Paths []string
+	// Blob ids of the affected file versions, sorted and without duplicates.
+	Blobs []string
+	// Base names of the affected files, BFG only matches on those.
+	FileNames []string
+}
+
+// Remediation resolves the given finding locations against the repository at
+// source and returns the lists git-filter-repo and BFG expect.
+func (s *SourceGit) Remediation(source string, locations []FindingLocation) (*RemediationLists, error) {
+	source = localSource(source)
+
+	repo, err := openGitRepo(source, SourceGitLoadOptions{})
+	if err != nil {
+		return nil, err
+	}
+	s.trackRepo(repo, isTemporarySource(source))
+	defer s.releaseRepo(repo)
+
+	paths := make(map[string]bool)
+	blobs := make(map[string]bool)
+	names := make(map[string]bool)
+
+	for _, loc := range locations {
+		if loc.Path == "" {
+			return nil, fmt.Errorf("finding location without path (commit %s)", loc.Commit)
+		}
+
+		blob := loc.Blob
+		if blob == "" && loc.Commit != "" {
+			blob, err = lookupBlobAtCommit(repo, loc.Commit, loc.Path)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		paths[loc.Path] = true
+		names[path.Base(loc.Path)] = true
+		if blob != "" {
+			blobs[blob] = true
+		}
+	}
+
+	return &RemediationLists{
+		Paths:     sortedKeys(paths),
+		Blobs:     sortedKeys(blobs),
+		FileNames: sortedKeys(names),
+	}, nil
+}
+
+// FilterRepoPaths returns the content of a file for
+// "git filter-repo --invert-paths --paths-from-file <file>".
+func (r *RemediationLists) FilterRepoPaths() []byte {
+	var lines []string
+	for _, p := range r.Paths {
+		// filter-repo treats these prefixes as pattern selectors.
+		if strings.HasPrefix(p, "regex:") || strings.HasPrefix(p, "glob:") || strings.HasPrefix(p, "literal:") {
+			p = "literal:" + p
+		}
+		lines = append(lines, p)
+	}
+
+	return joinLines(lines)
+}
+
+// FilterRepoBlobs returns the content of a file for
+// "git filter-repo --strip-blobs-with-ids <file>".
+func (r *RemediationLists) FilterRepoBlobs() []byte {
+	return joinLines(r.Blobs)
+}
+
+// BFGBlobs returns the content of a file for
+// "bfg --strip-blobs-with-ids <file>".
+func (r *RemediationLists) BFGBlobs() []byte {
+	return joinLines(r.Blobs)
+}
+
+// BFGDeleteFiles returns the glob for "bfg --delete-files <glob>".
+func (r *RemediationLists) BFGDeleteFiles() string {
+	if len(r.FileNames) == 1 {
+		return r.FileNames[0]
+	}
+
+	return "{" + strings.Join(r.FileNames, ",") + "}"
+}
+
+func lookupBlobAtCommit(repo *git.Repository, commitId string, filePath string) (string, error) {
+	oid, err := git.NewOid(commitId)
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := repo.LookupCommit(oid)
+	if err != nil {
+		return "", err
+	}
+	defer commit.Free()
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", err
+	}
+	defer tree.Free()
+
+	entry, err := tree.EntryByPath(filePath)
+	if err != nil {
+		return "", fmt.Errorf("%s not found in commit %s: %v", filePath, commitId, err)
+	}
+
+	return entry.Id.String(), nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func joinLines(lines []string) []byte {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
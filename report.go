@@ -0,0 +1,81 @@
+package sourcegit
+
+import (
+	"time"
+)
+
+// LoadReport describes what happened during a load beyond the objects that
+// were returned.
+type LoadReport struct {
+	// Hash algorithm of the repository objects ("sha1" or "sha256").
+	ObjectFormat string
+
+	// Full name of the default branch. For remotes, it is the branch the
+	// remote HEAD points to.
+	DefaultBranch string
+	// Former names of the default branch, most recent first, as found in
+	// the reflog of HEAD. State kept by branch name should be carried over
+	// from them instead of starting afresh.
+	DefaultBranchFormerNames []string
+	// Ref HEAD points to ("" when detached), and the branch renames in its
+	// reflog, oldest first.
+	HeadRef       string
+	BranchRenames []BranchRename
+	// Stable identifier of the repository, also set as "repo-fingerprint"
+	// on every object: "url:<host/path>" of the origin remote, or
+	// "root:<sha>" of the root commit.
+	Fingerprint string
+	// Whether the repository is a shallow clone, and how many replace refs
+	// were applied to the walk.
+	Shallow     bool
+	ReplaceRefs int
+
+	// Commits skipped by dedup-patch-id because an equivalent change had
+	// already been scanned.
+	DuplicateCommits int
+	// Blobs skipped because a previous load had returned them (see
+	// DedupCache).
+	DuplicateBlobs int
+	// Refs with commits left unscanned by ref-commit-budget.
+	RefsOverBudget []string
+
+	// Objects that could not be read and were skipped.
+	Corrupt []CorruptObject
+	// Blobs of a partial clone that were skipped or could not be fetched.
+	Promised []PromisedObject
+
+	// Directory holding the content of spooled objects (see the
+	// "spool-file" metadata). It is up to the caller to remove it.
+	SpoolDir string
+
+	// Where the load stopped when cut short by max-duration, nil when it
+	// completed.
+	Stopped *StopPoint
+
+	// GitHub API quota left after the last request, and when it is reset,
+	// for loads that enumerate content through the API.
+	RateLimitRemaining int
+	RateLimitReset     time.Time
+
+	// Problems that did not affect any particular object.
+	Warnings []string
+}
+
+// CorruptObject is an object that was missing or could not be read.
+type CorruptObject struct {
+	// Id of the unreadable object.
+	Id string
+	// Path of the file, when the object is a blob reached through a tree
+	// or the index.
+	Path string
+	// Commit the object was reached from, if any.
+	Commit string
+	Err    error
+}
+
+// PromisedObject is a blob a partial clone's promisor remote has not sent.
+type PromisedObject struct {
+	Id     string
+	Path   string
+	Commit string
+}
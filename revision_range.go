@@ -0,0 +1,45 @@
+package sourcegit
+
+import (
+	"fmt"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// revisionRangeRefs returns the scan ref of a revision range: "A..B" walks
+// the commits reachable from B and not from A, a single revision walks all
+// of its history.
+func revisionRangeRefs(repo *git.Repository, spec string) ([]scanRef, error) {
+	revspec, err := repo.Revparse(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if revspec.Flags()&git.RevparseSingle != 0 {
+		commit, err := revspec.From().Peel(git.ObjectCommit)
+		if err != nil {
+			return nil, err
+		}
+		defer commit.Free()
+
+		return []scanRef{{Name: spec, Target: commit.Id()}}, nil
+	}
+
+	if revspec.Flags()&git.RevparseMergeBase != 0 {
+		return nil, fmt.Errorf("revision-range %q: symmetric differences are not supported", spec)
+	}
+
+	from, err := revspec.From().Peel(git.ObjectCommit)
+	if err != nil {
+		return nil, err
+	}
+	defer from.Free()
+
+	to, err := revspec.To().Peel(git.ObjectCommit)
+	if err != nil {
+		return nil, err
+	}
+	defer to.Free()
+
+	return []scanRef{{Name: spec, Target: to.Id(), Hide: []*git.Oid{from.Id()}}}, nil
+}
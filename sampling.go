@@ -0,0 +1,60 @@
+package sourcegit
+
+import (
+	"fmt"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// commitSampler decides which commits a sampled scan looks at: every Nth
+// commit (sample-every-n) and/or one commit per day or week (sample-period).
+type commitSampler struct {
+	everyN    int
+	period    string
+	dateField string
+
+	count   int
+	periods map[string]bool
+}
+
+func newCommitSampler(opt SourceGitLoadOptions) *commitSampler {
+	return &commitSampler{
+		everyN:    opt.SampleEveryN,
+		period:    opt.SamplePeriod,
+		dateField: opt.DateField,
+		periods:   make(map[string]bool),
+	}
+}
+
+func (s *commitSampler) keep(commit *git.Commit) bool {
+	if s.everyN > 1 {
+		s.count++
+		if (s.count-1)%s.everyN != 0 {
+			return false
+		}
+	}
+
+	if s.period != "" {
+		key := s.periodKey(commit)
+		if s.periods[key] {
+			return false
+		}
+		s.periods[key] = true
+	}
+
+	return true
+}
+
+func (s *commitSampler) periodKey(commit *git.Commit) string {
+	when := commitTime(commit, s.dateField).UTC()
+
+	switch s.period {
+	case "week":
+		year, week := when.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case "month":
+		return when.Format("2006-01")
+	}
+
+	return when.Format("2006-01-02")
+}
@@ -0,0 +1,53 @@
+package sourcegit
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// sandboxCommand prepares an invocation of the git CLI for sandbox-clone.
+// It inherits no environment but PATH: HOME is an empty directory and the
+// system configuration is skipped, so no configuration, credential helper or
+// proxy of the host applies, and it never prompts. On Unix systems, the
+// resources of git and its children (remote helpers, ssh) are bounded by
+// sandbox-memory-limit and sandbox-cpu-limit. The returned function removes
+// what the sandbox leaves behind once the command is over.
+func sandboxCommand(source string, opt SourceGitLoadOptions, args ...string) (*exec.Cmd, func(), error) {
+	home, err := tempDir("sandbox", source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("git", args...)
+	} else {
+		// Limits set by ulimit apply to the shell and are inherited by the
+		// git it execs.
+		limits := ""
+		if opt.SandboxMemoryLimit > 0 {
+			limits += fmt.Sprintf("ulimit -v %d && ", opt.SandboxMemoryLimit/1024)
+		}
+		if opt.SandboxCpuLimit > 0 {
+			limits += fmt.Sprintf("ulimit -t %d && ", opt.SandboxCpuLimit)
+		}
+		cmd = exec.Command("/bin/sh", append([]string{"-c", limits + `exec git "$@"`, "git"}, args...)...)
+	}
+
+	cmd.Dir = home
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + home,
+		"XDG_CONFIG_HOME=" + home,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_ASKPASS=",
+		"SSH_ASKPASS=",
+		"GIT_SSH_COMMAND=ssh -o BatchMode=yes",
+		"GIT_PROTOCOL_FROM_USER=0",
+	}
+
+	return cmd, func() { removeTempDir(home) }, nil
+}
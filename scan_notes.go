@@ -0,0 +1,115 @@
+package sourcegit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// DefaultScanNotesRef is the notes ref scan results are recorded in, unless
+// another one is given.
+const DefaultScanNotesRef = "refs/notes/seekret"
+
+// ScanNote is the result of scanning a commit, to be recorded as a git note
+// on it.
+type ScanNote struct {
+	Commit string
+	// Whether the scan found anything in the commit.
+	Dirty     bool
+	ScannedAt time.Time
+}
+
+func (n ScanNote) text() string {
+	result := "clean"
+	if n.Dirty {
+		result = "dirty"
+	}
+
+	return fmt.Sprintf("seekret-scan: %s\nscanned-at: %s\n", result, n.ScannedAt.UTC().Format(time.RFC3339))
+}
+
+// WriteScanNotes records scan results as notes on the scanned commits of the
+// repository at source, in notesRef (DefaultScanNotesRef when empty),
+// replacing previous ones. With push, the notes ref is then pushed to origin
+// so that loads elsewhere with scan-notes-ref skip these commits too. Notes
+// of remote sources are only kept if pushed.
+func (s *SourceGit) WriteScanNotes(source string, notesRef string, notes []ScanNote, push bool) error {
+	if notesRef == "" {
+		notesRef = DefaultScanNotesRef
+	}
+	source = localSource(source)
+
+	repo, err := openGitRepo(source, SourceGitLoadOptions{})
+	if err != nil {
+		return err
+	}
+	s.trackRepo(repo, isTemporarySource(source))
+	defer s.releaseRepo(repo)
+
+	if push {
+		// Build on the notes already shared, or the push is rejected.
+		if err := fetchScanNotes(repo, notesRef); err != nil {
+			return err
+		}
+	}
+
+	sig, err := repo.DefaultSignature()
+	if err != nil {
+		sig = &git.Signature{Name: "seekret", Email: "seekret@localhost", When: time.Now()}
+	}
+
+	for _, note := range notes {
+		id, err := git.NewOid(note.Commit)
+		if err != nil {
+			return fmt.Errorf("invalid commit %q: %v", note.Commit, err)
+		}
+		if note.ScannedAt.IsZero() {
+			note.ScannedAt = time.Now()
+		}
+
+		if _, err := repo.Notes.Create(notesRef, sig, sig, id, note.text(), true); err != nil {
+			return err
+		}
+	}
+
+	if !push {
+		return nil
+	}
+
+	remote, err := repo.Remotes.Lookup("origin")
+	if err != nil {
+		return err
+	}
+	defer remote.Free()
+
+	return remote.Push([]string{notesRef + ":" + notesRef}, &git.PushOptions{
+		RemoteCallbacks: newRemoteCallbacks(""),
+	})
+}
+
+// fetchScanNotes updates notesRef from origin. Repositories without origin,
+// and origins without the notes ref, are left as they are.
+func fetchScanNotes(repo *git.Repository, notesRef string) error {
+	remote, err := repo.Remotes.Lookup("origin")
+	if err != nil {
+		return nil
+	}
+	defer remote.Free()
+
+	return remote.Fetch([]string{"+" + notesRef + ":" + notesRef}, &git.FetchOptions{
+		RemoteCallbacks: newRemoteCallbacks(""),
+	}, "")
+}
+
+// scannedCommit reports whether commit has a note in notesRef, i.e. was
+// already scanned.
+func scannedCommit(repo *git.Repository, notesRef string, commit *git.Commit) bool {
+	note, err := repo.Notes.Read(notesRef, commit.Id())
+	if err != nil {
+		return false
+	}
+	note.Free()
+
+	return true
+}
@@ -0,0 +1,140 @@
+package sourcegit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// scanState records the ref tips a load walked, so that the next load of the
+// source with since-last-scan only walks the commits added since. Commits
+// are told apart by reachability (old tip..new tip), not by date: commits
+// with dates in the past, from clock skew or rebases, are never skipped.
+type scanState struct {
+	path  string
+	state scanStateFile
+	// Tips of this load, written once it completes.
+	tips []string
+}
+
+type scanStateFile struct {
+	Source    string    `json:"source"`
+	Tips      []string  `json:"tips"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// walkRestrictions returns the options of opt that keep the history walk
+// from reaching every commit between the recorded tips and the current ones.
+// Saving the tips of such a walk would hide the commits it skipped from every
+// later load, so they cannot be combined with since-last-scan.
+func (opt SourceGitLoadOptions) walkRestrictions() []string {
+	var restrictions []string
+	for _, r := range []struct {
+		name string
+		set  bool
+	}{
+		{"commit-count", opt.CommitCount > 0},
+		{"exclude-commits", len(opt.ExcludeCommits) > 0},
+		{"ref-commit-budget", opt.RefCommitBudget > 0},
+		{"since", !opt.Since.IsZero()},
+		{"until", !opt.Until.IsZero()},
+		{"sample-every-n", opt.SampleEveryN > 1},
+		{"sample-period", opt.SamplePeriod != ""},
+		{"untrusted-authors", len(opt.UntrustedAuthors) > 0},
+		{"scan-notes-ref", opt.ScanNotesRef != ""},
+		{"ignore-revs", len(opt.IgnoreRevs) > 0},
+		{"ignore-revs-file", opt.IgnoreRevsFile != ""},
+	} {
+		if r.set {
+			restrictions = append(restrictions, r.name)
+		}
+	}
+
+	return restrictions
+}
+
+// loadScanState reads the state left by the previous load of source. A
+// missing file is not an error: the first load walks the whole history.
+func loadScanState(path string, source string) (*scanState, error) {
+	s := &scanState{
+		path:  path,
+		state: scanStateFile{Source: source},
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	var state scanStateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid scan state %s: %v", path, err)
+	}
+	if state.Source != source {
+		return nil, fmt.Errorf("scan state %s belongs to source %s", path, state.Source)
+	}
+	s.state = state
+
+	return s, nil
+}
+
+// hide hides the tips of the previous load from every ref, and records the
+// tips of this one. Previous tips no longer in the repository, e.g. after a
+// force push, are left out: their refs are walked in full.
+func (s *scanState) hide(repo *git.Repository, refs []scanRef) {
+	if s == nil {
+		return
+	}
+
+	for _, tip := range s.state.Tips {
+		oid, err := git.NewOid(tip)
+		if err != nil {
+			continue
+		}
+		commit, err := repo.LookupCommit(oid)
+		if err != nil {
+			continue
+		}
+		commit.Free()
+
+		for i := range refs {
+			refs[i].Hide = append(refs[i].Hide, oid)
+		}
+	}
+
+	for _, ref := range refs {
+		s.tips = append(s.tips, ref.Target.String())
+	}
+}
+
+// save writes the tips of a load that completed. Loads not walking history
+// keep the previous tips.
+func (s *scanState) save() error {
+	if s == nil {
+		return nil
+	}
+
+	if s.tips != nil {
+		s.state.Tips = s.tips
+	}
+	s.state.UpdatedAt = time.Now().UTC()
+
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,204 @@
+package sourcegit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/apuigsech/seekret/models"
+)
+
+// objectsFromSha256 loads the local repository at path, in the SHA-256
+// object format libgit2 cannot open, with the git CLI. It covers the
+// files (commit-files) and messages (commit-messages) of the commits walked
+// from HEAD, or from the refs selected by all-branches and refs, up to
+// commit-count commits of each. What else needs libgit2 is left out, with a
+// warning in the load report.
+func objectsFromSha256(path string, opt SourceGitLoadOptions, collector *objectCollector) error {
+	collector.report.ObjectFormat = objectFormatSha256
+	collector.report.Warnings = append(collector.report.Warnings, "SHA-256 repository read with the git CLI: only commit-files and commit-messages are loaded")
+
+	refs := []string{"HEAD"}
+	if opt.multiRef() {
+		out, err := gitCommand(path, "for-each-ref", "--format=%(refname)", "refs/heads", "refs/remotes", "refs/tags").Output()
+		if err != nil {
+			return fmt.Errorf("listing refs of %s: %v", path, err)
+		}
+		refs = nil
+		for _, name := range strings.Fields(string(out)) {
+			if matchRefGlobs(name, opt.ExcludeRefs) {
+				continue
+			}
+			if len(opt.Refs) == 0 && (strings.HasPrefix(name, "refs/heads/") || strings.HasPrefix(name, "refs/remotes/")) || matchRefGlobs(name, opt.Refs) {
+				refs = append(refs, name)
+			}
+		}
+	}
+
+	objects, err := newCatFile(path)
+	if err != nil {
+		return err
+	}
+	defer objects.close()
+
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		args := []string{"rev-list"}
+		if opt.CommitCount > 0 {
+			args = append(args, "--max-count="+strconv.Itoa(opt.CommitCount))
+		}
+		out, err := gitCommand(path, append(args, ref, "--")...).Output()
+		if err != nil {
+			return fmt.Errorf("walking %s of %s: %v", ref, path, err)
+		}
+
+		for _, id := range strings.Fields(string(out)) {
+			if collector.outOfTime() {
+				return collector.stop(ref, id)
+			}
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			collector.metricAdd(MetricCommitsWalked, 1)
+
+			objectList, err := objectsFromSha256Commit(path, id, opt, objects, collector)
+			if err != nil {
+				return err
+			}
+			if opt.multiRef() {
+				for i := range objectList {
+					objectList[i].SetMetadata("branch", ref, models.MetadataAttributes{})
+				}
+			}
+			if err := collector.add(objectList...); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// objectsFromSha256Commit returns the objects of commit id, read with
+// objects.
+func objectsFromSha256Commit(path string, id string, opt SourceGitLoadOptions, objects *catFile, collector *objectCollector) ([]models.Object, error) {
+	var objectList []models.Object
+
+	if opt.CommitMessages {
+		raw, err := objects.read(id)
+		if err != nil {
+			return nil, err
+		}
+		message := ""
+		if i := bytes.Index(raw, []byte("\n\n")); i >= 0 {
+			message = string(raw[i+2:])
+		}
+		o := models.NewObject(fmt.Sprintf("commit-%s", id), Type, "commit-message", []byte(message))
+		o.SetMetadata("commit", id, models.MetadataAttributes{})
+		objectList = append(objectList, *o)
+	}
+
+	if !opt.CommitFiles {
+		return objectList, nil
+	}
+
+	out, err := gitCommand(path, "ls-tree", "-r", "-z", id).Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing files of %s: %v", id, err)
+	}
+
+	// "<mode> <type> <id>\t<path>" entries.
+	for _, entry := range strings.Split(string(out), "\x00") {
+		tab := strings.Index(entry, "\t")
+		if tab < 0 {
+			continue
+		}
+		fields := strings.Fields(entry[:tab])
+		if len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		name, blobId := entry[tab+1:], fields[2]
+
+		if collector.tooDeep(name, false) || collector.skippedBlob(blobId) {
+			continue
+		}
+
+		content, err := objects.read(blobId)
+		if err != nil {
+			if err := collector.corrupt(blobId, name, id, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		o := models.NewObject(name, Type, "file-content", content)
+		o.SetMetadata("commit", id, models.MetadataAttributes{})
+		setUniqId(o, blobId)
+		objectList = append(objectList, *o)
+	}
+
+	return objectList, nil
+}
+
+// catFile reads objects through a "git cat-file --batch" process.
+type catFile struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+func newCatFile(path string) (*catFile, error) {
+	cmd := gitCommand(path, "cat-file", "--batch")
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &catFile{cmd: cmd, in: in, out: bufio.NewReader(out)}, nil
+}
+
+// read returns the content of object id.
+func (c *catFile) read(id string) ([]byte, error) {
+	if _, err := io.WriteString(c.in, id+"\n"); err != nil {
+		return nil, err
+	}
+
+	// "<id> <type> <size>", or "<id> missing".
+	header, err := c.out.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("reading object %s: %s", id, strings.TrimSpace(header))
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("reading object %s: %s", id, strings.TrimSpace(header))
+	}
+
+	// The content is followed by a newline.
+	content := make([]byte, size+1)
+	if _, err := io.ReadFull(c.out, content); err != nil {
+		return nil, err
+	}
+
+	return content[:size], nil
+}
+
+func (c *catFile) close() error {
+	c.in.Close()
+	return c.cmd.Wait()
+}
@@ -0,0 +1,74 @@
+package sourcegit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// openGitRepoRemoteShallow clones only the tip of the default branch of a
+// remote, for head-only scans. libgit2 cannot do shallow clones, so the git
+// CLI does the clone, with the given clone options added.
+func openGitRepoRemoteShallow(gitUri string, opt SourceGitLoadOptions, options ...string) (*git.Repository, error) {
+	return openGitRepoRemoteCli(gitUri, opt, append([]string{"--depth", "1", "--single-branch", "--no-tags"}, options...)...)
+}
+
+// openGitRepoRemoteCli clones a remote with the git CLI, passing it the
+// given clone options, for what libgit2 cannot do. With sandbox-clone, the
+// git CLI runs in a restricted subprocess. It is killed at max-duration.
+func openGitRepoRemoteCli(gitUri string, opt SourceGitLoadOptions, options ...string) (*git.Repository, error) {
+	tmpdir, err := tempDir("clone", gitUri)
+	if err != nil {
+		return nil, err
+	}
+
+	// Never checked out, see indexHead.
+	args := append([]string{"clone", "--quiet", "--no-checkout"}, options...)
+	args = append(args, "--", gitUri, tmpdir)
+
+	cmd := gitCommand("", args...)
+	if opt.SandboxClone {
+		var done func()
+		cmd, done, err = sandboxCommand(gitUri, opt, args...)
+		if err != nil {
+			removeTempDir(tmpdir)
+			return nil, err
+		}
+		defer done()
+	}
+
+	if out, err := outputUntil(cmd, opt.deadline); err != nil {
+		removeTempDir(tmpdir)
+		if err == errOutOfTime {
+			return nil, fmt.Errorf("cloning %s: %v", gitUri, err)
+		}
+		return nil, remoteError(gitUri, fmt.Errorf("clone failed: %s", strings.TrimSpace(string(out))))
+	}
+
+	// Clones filtered by the server are partial clones.
+	repo, err := git.OpenRepository(tmpdir)
+	if err != nil {
+		remote := promisorRemote(tmpdir)
+		if remote == "" {
+			removeTempDir(tmpdir)
+			return nil, err
+		}
+		if repo, err = openPartialClone(tmpdir, remote); err != nil {
+			removeTempDir(tmpdir)
+			return nil, err
+		}
+	}
+
+	// HEAD misses the trees clone-tree-filter left out.
+	if !opt.CloneTreeFilter || opt.MaxPathDepth <= 0 {
+		if err := indexHead(repo); err != nil {
+			path := repo.Path()
+			repo.Free()
+			releasePartialView(path)
+			return nil, err
+		}
+	}
+
+	return repo, nil
+}
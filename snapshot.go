@@ -0,0 +1,197 @@
+package sourcegit
+
+import (
+	"bytes"
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// stashSourceRegexp matches a stash entry of the repository in the current
+// directory ("stash@{1}") or of the repository at a path
+// ("path/to/repo#stash@{1}").
+var stashSourceRegexp = regexp.MustCompile(`^(?:(.+)#)?(stash@\{\d+\})$`)
+
+// stashSource splits a stash source into the repository path and the stash
+// entry.
+func stashSource(source string) (string, string, bool) {
+	m := stashSourceRegexp.FindStringSubmatch(source)
+	if m == nil {
+		return "", "", false
+	}
+	if m[1] == "" {
+		m[1] = "."
+	}
+
+	return m[1], m[2], true
+}
+
+// isIndexFileSource reports whether source is a git index file (e.g.
+// .git/index, or one saved with GIT_INDEX_FILE), recognized by its
+// signature.
+func isIndexFileSource(source string) bool {
+	f, err := os.Open(source)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	signature := make([]byte, 4)
+	if _, err := io.ReadFull(f, signature); err != nil {
+		return false
+	}
+
+	return bytes.Equal(signature, []byte("DIRC"))
+}
+
+// openGitRepoSnapshot opens the repository a stash entry or an index file
+// belongs to. Index files are looked up from their directory, or from the
+// current directory when they are outside of any repository.
+func openGitRepoSnapshot(source string, opt SourceGitLoadOptions) (*git.Repository, error) {
+	if path, _, ok := stashSource(source); ok {
+		return openGitRepoLocal(path, opt)
+	}
+
+	if repo, err := openGitRepoLocal(filepath.Dir(source), opt); err == nil {
+		return repo, nil
+	}
+
+	return openGitRepoLocal(".", opt)
+}
+
+// objectsFromStash emits the files a stash entry changes relative to the
+// commit it was created on: the ones changed in the working directory, the
+// ones staged, and the untracked ones when the stash keeps them. Objects
+// carry "stash" metadata.
+func objectsFromStash(repo *git.Repository, entry string, collector *objectCollector) error {
+	obj, err := repo.RevparseSingle(entry)
+	if err != nil {
+		return err
+	}
+	id := obj.Id()
+	obj.Free()
+
+	stash, err := repo.LookupCommit(id)
+	if err != nil {
+		return err
+	}
+	defer stash.Free()
+
+	var base *git.Tree
+	if stash.ParentCount() > 0 {
+		if parent := stash.Parent(0); parent != nil {
+			base, err = parent.Tree()
+			parent.Free()
+			if err != nil {
+				return err
+			}
+			defer base.Free()
+		}
+	}
+
+	// Working directory, index and untracked files commits, in this order.
+	commits := []*git.Commit{stash}
+	for i := uint(1); i < stash.ParentCount() && i <= 2; i++ {
+		if parent := stash.Parent(i); parent != nil {
+			defer parent.Free()
+			commits = append(commits, parent)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, commit := range commits {
+		tree, err := commit.Tree()
+		if err != nil {
+			return err
+		}
+		defer tree.Free()
+
+		// The untracked files commit has no parent to diff against.
+		from := base
+		if i == 2 {
+			from = nil
+		}
+
+		diff, err := repo.DiffTreeToTree(from, tree, nil)
+		if err != nil {
+			return err
+		}
+		defer diff.Free()
+
+		count, err := diff.NumDeltas()
+		if err != nil {
+			return err
+		}
+		for d := 0; d < count; d++ {
+			delta, err := diff.GetDelta(d)
+			if err != nil {
+				return err
+			}
+			id := delta.NewFile.Oid.String()
+			if delta.Status == git.DeltaDeleted || seen[delta.NewFile.Path+id] {
+				continue
+			}
+			seen[delta.NewFile.Path+id] = true
+
+			blob, err := repo.LookupBlob(delta.NewFile.Oid)
+			if err != nil {
+				if err := collector.corrupt(id, delta.NewFile.Path, commit.Id().String(), err); err != nil {
+					return err
+				}
+				continue
+			}
+
+			o := models.NewObject(delta.NewFile.Path, Type, "file-content", blob.Contents())
+			blob.Free()
+			o.SetMetadata("commit", commit.Id().String(), models.MetadataAttributes{})
+			o.SetMetadata("stash", entry, models.MetadataAttributes{})
+			setUniqId(o, id)
+			if err := collector.add(*o); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// objectsFromIndexFile emits the content of every file staged in the index
+// file at path, with "index-file" metadata.
+func objectsFromIndexFile(repo *git.Repository, path string, collector *objectCollector) error {
+	index, err := git.OpenIndex(path)
+	if err != nil {
+		return err
+	}
+	defer index.Free()
+
+	for i := uint(0); i < index.EntryCount(); i++ {
+		entry, err := index.EntryByIndex(i)
+		if err != nil {
+			return err
+		}
+		if collector.excludedPath(entry.Path) {
+			continue
+		}
+
+		blob, err := repo.LookupBlob(entry.Id)
+		if err != nil {
+			if err := collector.corrupt(entry.Id.String(), entry.Path, "", err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		o := models.NewObject(entry.Path, Type, "file-content", blob.Contents())
+		blob.Free()
+		o.SetMetadata("index-file", path, models.MetadataAttributes{})
+		setUniqId(o, entry.Id.String())
+		if err := collector.add(*o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package sourcegit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/apuigsech/seekret/models"
+)
+
+// contentSpool moves object contents out of memory into files of a
+// temporary directory. Spooled objects have no Content and carry the path of
+// their file in the "spool-file" metadata.
+type contentSpool struct {
+	dir   string
+	count int
+}
+
+// newContentSpool creates the spool directory inside parent, or inside the
+// default temporary directory when parent is empty.
+func newContentSpool(parent string) (*contentSpool, error) {
+	dir, err := ioutil.TempDir(parent, "seekret-spool")
+	if err != nil {
+		return nil, err
+	}
+
+	return &contentSpool{dir: dir}, nil
+}
+
+func (s *contentSpool) store(o *models.Object) error {
+	if o.Content == nil {
+		return nil
+	}
+
+	s.count++
+	file := filepath.Join(s.dir, fmt.Sprintf("%08d", s.count))
+	if err := ioutil.WriteFile(file, o.Content, 0600); err != nil {
+		return err
+	}
+
+	o.Content = nil
+	o.SetMetadata("spool-file", file, models.MetadataAttributes{})
+
+	return nil
+}
+
+func (s *contentSpool) remove() error {
+	return os.RemoveAll(s.dir)
+}
+
+// OpenObjectContent returns a reader for the content of o, wherever it is:
+// in memory, or in the spool directory for spooled objects.
+func OpenObjectContent(o *models.Object) (io.ReadCloser, error) {
+	if file, err := o.GetMetadata("spool-file"); err == nil && file != "" {
+		return os.Open(file)
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(o.Content)), nil
+}
+
+// ReadObjectContent returns the whole content of o, reading it from the
+// spool directory for spooled objects.
+func ReadObjectContent(o *models.Object) ([]byte, error) {
+	if file, err := o.GetMetadata("spool-file"); err == nil && file != "" {
+		return ioutil.ReadFile(file)
+	}
+
+	return o.Content, nil
+}
@@ -0,0 +1,137 @@
+package sourcegit
+
+import (
+	"fmt"
+	"github.com/emptyinterface/sshconfig"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	sshUrlRegexp = regexp.MustCompile(`^ssh://(?:([^@/]+)@)?([^:/]+)(?::(\d+))?/(.+)$`)
+	sshScpRegexp = regexp.MustCompile(`^(?:([^@/]+)@)?([^:/]+):(.+)$`)
+)
+
+// loadSshConfig parses the ssh_config of the user, or returns nil when there
+// is none or it cannot be read.
+func loadSshConfig() *sshconfig.Config {
+	fh, err := os.Open(os.ExpandEnv("$HOME/.ssh/config"))
+	if err != nil {
+		return nil
+	}
+	defer fh.Close()
+
+	c, err := sshconfig.Parse(fh)
+	if err != nil {
+		return nil
+	}
+
+	return c
+}
+
+// sshHostParam returns the value of keyword in the ssh_config section of
+// host, or "" when not set.
+func sshHostParam(c *sshconfig.Config, host string, keyword string) string {
+	if c == nil {
+		return ""
+	}
+
+	h := c.FindByHostname(host)
+	if h == nil {
+		return ""
+	}
+
+	p := h.GetParam(keyword)
+	if p == nil {
+		return ""
+	}
+
+	return p.Value()
+}
+
+// sshProxied reports whether gitUri, given as the ssh_config alias when not
+// empty, is an SSH remote only reachable through a ProxyJump or ProxyCommand
+// of ssh_config. libssh2, which libgit2 uses, ignores both, so such remotes
+// are cloned with the git CLI and OpenSSH.
+func sshProxied(gitUri string, alias string) bool {
+	u, err := url.Parse(gitUri)
+	if err != nil || u.Scheme != "ssh" {
+		return false
+	}
+
+	c := loadSshConfig()
+	for _, keyword := range []string{"ProxyJump", "ProxyCommand"} {
+		var v string
+		if alias != "" {
+			v = sshHostParam(c, alias, keyword)
+		}
+		if v == "" {
+			v = sshHostParam(c, u.Hostname(), keyword)
+		}
+		if v != "" && !strings.EqualFold(v, "none") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sshConfigUri returns the effective URL of an SSH source once the Host
+// section of ssh_config for its host has been applied: HostName (with %h),
+// User and Port, the ones in the source taking precedence. libssh2 reads none
+// of them, so "myalias:repo.git" would otherwise not resolve. scp-like
+// sources without a user ("myalias:repo.git") are only taken as SSH when
+// ssh_config gives a HostName for them, so local paths are left alone. The
+// alias is the Host the HostName was found for, if any.
+func sshConfigUri(source string) (string, string, bool) {
+	var user, host, port, path, alias string
+	if m := sshUrlRegexp.FindStringSubmatch(source); m != nil {
+		user, host, port, path = m[1], m[2], m[3], "/"+m[4]
+	} else if m := sshScpRegexp.FindStringSubmatch(source); m != nil && !strings.Contains(source, "://") {
+		user, host, path = m[1], m[2], m[3]
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	} else {
+		return "", "", false
+	}
+
+	c := loadSshConfig()
+	hostName := sshHostParam(c, host, "HostName")
+	configUser := sshHostParam(c, host, "User")
+	configPort := sshHostParam(c, host, "Port")
+	if hostName == "" && (configUser == "" && configPort == "" || user == "" && !strings.HasPrefix(source, "ssh://")) {
+		return "", "", false
+	}
+
+	if hostName != "" {
+		alias = host
+		host = strings.Replace(hostName, "%h", host, -1)
+	}
+	if user == "" {
+		user = configUser
+	}
+	if port == "" {
+		port = configPort
+	}
+
+	uri := "ssh://"
+	if user != "" {
+		uri += user + "@"
+	}
+	uri += host
+	if port != "" && port != "22" {
+		uri += fmt.Sprintf(":%s", port)
+	}
+
+	return uri + path, alias, true
+}
+
+// sshSourceAlias returns the ssh_config Host alias source is given as, or ""
+// when it is not given through an alias.
+func sshSourceAlias(source string) string {
+	_, alias, _ := sshConfigUri(insteadOf(source))
+	return alias
+}
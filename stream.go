@@ -0,0 +1,194 @@
+package sourcegit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/apuigsech/seekret"
+	"github.com/apuigsech/seekret/models"
+)
+
+// StreamRecord is what stream-file holds for every object, one JSON document
+// per line for "jsonl", or consecutive values of a single gob stream for
+// "gob".
+type StreamRecord struct {
+	Type     string            `json:"type"`
+	SubType  string            `json:"subtype"`
+	Name     string            `json:"name"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Base64 in JSON Lines. Empty with stream-content "hash" or "none".
+	Content []byte `json:"content,omitempty"`
+	// Hex SHA-256 of the content, with stream-content "hash".
+	ContentSha256 string `json:"content_sha256,omitempty"`
+}
+
+// NewStreamRecord returns the record of o, with its whole content.
+func NewStreamRecord(o *models.Object) (*StreamRecord, error) {
+	return streamRecord(o, "full")
+}
+
+func streamRecord(o *models.Object, contentMode string) (*StreamRecord, error) {
+	content, err := ReadObjectContent(o)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &StreamRecord{
+		Type:    o.Type,
+		SubType: o.SubType,
+		Name:    o.Name,
+	}
+
+	for k := range o.Metadata {
+		if k == "spool-file" {
+			continue
+		}
+		if record.Metadata == nil {
+			record.Metadata = make(map[string]string)
+		}
+		record.Metadata[k], _ = o.GetMetadata(k)
+	}
+
+	switch contentMode {
+	case "hash":
+		sum := sha256.Sum256(content)
+		record.ContentSha256 = hex.EncodeToString(sum[:])
+	case "none":
+	default:
+		record.Content = content
+	}
+
+	return record, nil
+}
+
+// Object returns the object r was recorded from.
+func (r *StreamRecord) Object() *models.Object {
+	o := models.NewObject(r.Name, r.Type, r.SubType, r.Content)
+	for k, v := range r.Metadata {
+		o.SetMetadata(k, v, models.MetadataAttributes{})
+	}
+
+	return o
+}
+
+// objectStream writes the objects of a load to stream-file as they are
+// emitted, instead of returning them.
+type objectStream struct {
+	fh      *os.File
+	w       *bufio.Writer
+	content string
+
+	json *json.Encoder
+	gob  *gob.Encoder
+}
+
+func newObjectStream(opt SourceGitLoadOptions) (*objectStream, error) {
+	switch opt.StreamContent {
+	case "", "full", "hash", "none":
+	default:
+		return nil, fmt.Errorf("unknown stream-content %q", opt.StreamContent)
+	}
+
+	if opt.StreamFormat != "" && opt.StreamFormat != "jsonl" && opt.StreamFormat != "gob" {
+		return nil, fmt.Errorf("unknown stream-format %q", opt.StreamFormat)
+	}
+
+	fh, err := os.OpenFile(opt.StreamFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &objectStream{
+		fh:      fh,
+		w:       bufio.NewWriter(fh),
+		content: opt.StreamContent,
+	}
+	if opt.StreamFormat == "gob" {
+		s.gob = gob.NewEncoder(s.w)
+	} else {
+		s.json = json.NewEncoder(s.w)
+	}
+
+	return s, nil
+}
+
+func (s *objectStream) write(o *models.Object) error {
+	record, err := streamRecord(o, s.content)
+	if err != nil {
+		return err
+	}
+
+	if s.gob != nil {
+		return s.gob.Encode(record)
+	}
+	return s.json.Encode(record)
+}
+
+// close flushes the stream. The file is kept even for failed loads, with
+// the objects emitted until the failure.
+func (s *objectStream) close() error {
+	if s == nil {
+		return nil
+	}
+
+	if err := s.w.Flush(); err != nil {
+		s.fh.Close()
+		return err
+	}
+
+	return s.fh.Close()
+}
+
+// LoadObjectsFunc loads the objects of source like LoadObjectsWithReport,
+// but calls fn with every object as soon as it is emitted instead of
+// returning them, so that they can be sent elsewhere without holding them
+// all in memory. An error from fn aborts the load. stream-file is ignored,
+// and its restrictions apply.
+// Sources covering several repositories (gist listings, include-wiki) only
+// emit their objects once they are all loaded.
+func (s *SourceGit) LoadObjectsFunc(source string, opta seekret.LoadOptions, fn func(o *models.Object) error) (*LoadReport, error) {
+	_, report, repo, err := s.loadTo(source, opta, fn)
+	if repo != nil {
+		s.releaseRepo(repo)
+	}
+
+	return report, err
+}
+
+// emitObjects hands objectList to emit, or writes it to stream-file, for
+// the loads merging the objects of several repositories (gists of a user,
+// wiki), which cannot do it as they go. It returns what the load has to
+// return.
+func emitObjects(opt SourceGitLoadOptions, objectList []models.Object, emit func(o *models.Object) error) ([]models.Object, error) {
+	if emit != nil {
+		for i := range objectList {
+			if err := emit(&objectList[i]); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	if opt.StreamFile == "" {
+		return objectList, nil
+	}
+
+	stream, err := newObjectStream(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range objectList {
+		if err := stream.write(&objectList[i]); err != nil {
+			stream.close()
+			return nil, err
+		}
+	}
+
+	return nil, stream.close()
+}
@@ -0,0 +1,54 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret"
+)
+
+// subSourceOptions returns the load options for the repositories scanned on
+// behalf of another source (gists of a user, wikis, forks). They are the
+// same, except for the checkpoint, the since-last-scan state and the
+// evidence, which belong to the original source.
+func subSourceOptions(o seekret.LoadOptions) seekret.LoadOptions {
+	sub := make(seekret.LoadOptions, len(o))
+	for k, v := range o {
+		sub[k] = v
+	}
+	delete(sub, "checkpoint-file")
+	delete(sub, "since-last-scan")
+	delete(sub, "resume")
+	delete(sub, "evidence-file")
+	delete(sub, "stream-file")
+
+	return sub
+}
+
+// merge adds what happened loading a repository scanned on behalf of the
+// source of the report.
+func (r *LoadReport) merge(sub *LoadReport) {
+	if sub == nil {
+		return
+	}
+
+	r.Shallow = r.Shallow || sub.Shallow
+	r.ReplaceRefs += sub.ReplaceRefs
+	r.DuplicateCommits += sub.DuplicateCommits
+	r.DuplicateBlobs += sub.DuplicateBlobs
+	r.RefsOverBudget = append(r.RefsOverBudget, sub.RefsOverBudget...)
+	if r.Stopped == nil {
+		r.Stopped = sub.Stopped
+	}
+	r.Corrupt = append(r.Corrupt, sub.Corrupt...)
+	r.Promised = append(r.Promised, sub.Promised...)
+	if sub.SpoolDir != "" {
+		if r.SpoolDir == "" {
+			r.SpoolDir = sub.SpoolDir
+		} else {
+			r.Warnings = append(r.Warnings, "content spooled to "+sub.SpoolDir)
+		}
+	}
+	if !sub.RateLimitReset.IsZero() {
+		r.RateLimitRemaining = sub.RateLimitRemaining
+		r.RateLimitReset = sub.RateLimitReset
+	}
+	r.Warnings = append(r.Warnings, sub.Warnings...)
+}
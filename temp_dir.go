@@ -0,0 +1,195 @@
+package sourcegit
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Temporary repositories live in a directory of the system temporary
+// directory of their own for every user, named "<kind>-<repo>-<pid>-<random>":
+// <repo> is a short hash of the normalized source, and <pid> the process that
+// created it. PIDs cannot tell whether that process is still running, as
+// hosts sharing the temporary directory (containers) have PID namespaces of
+// their own: processes touch their directories every tempHeartbeat instead,
+// and the directories not touched for tempOrphanAge are the leftovers of
+// crashed runs. The age leaves room for processes stopped or starved for a
+// while, by a suspended host or an overloaded one.
+var (
+	tempRoot   = filepath.Join(os.TempDir(), tempRootName())
+	tempPurged sync.Once
+
+	tempLiveMu sync.Mutex
+	tempLive   = make(map[string]bool)
+	// Stops the heartbeat, nil when it is not running.
+	tempBeatStop chan struct{}
+)
+
+const (
+	tempOrphanAge = 6 * time.Hour
+	tempHeartbeat = 10 * time.Minute
+)
+
+func tempRootName() string {
+	if uid := os.Getuid(); uid >= 0 {
+		return "seekret-source-git-" + strconv.Itoa(uid)
+	}
+
+	return "seekret-source-git"
+}
+
+// tempDir creates a temporary directory for a repository of the given kind
+// ("clone", "bundle", "pack") opened from source. The first call of the
+// process removes the orphans of previous runs.
+func tempDir(kind string, source string) (string, error) {
+	if err := openTempRoot(); err != nil {
+		return "", err
+	}
+
+	tempPurged.Do(func() {
+		PurgeTemp()
+	})
+
+	sum := sha1.Sum([]byte(normalizeRemoteUrl(source)))
+	prefix := fmt.Sprintf("%s-%s-%d-", kind, hex.EncodeToString(sum[:4]), os.Getpid())
+
+	dir, err := ioutil.TempDir(tempRoot, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	tempLiveMu.Lock()
+	tempLive[dir] = true
+	if tempBeatStop == nil {
+		tempBeatStop = make(chan struct{})
+		go heartbeatTemp(tempBeatStop)
+	}
+	tempLiveMu.Unlock()
+
+	return dir, nil
+}
+
+// removeTempDir removes a directory created by tempDir. The heartbeat stops
+// with the last directory of the process.
+func removeTempDir(dir string) error {
+	err := os.RemoveAll(dir)
+
+	tempLiveMu.Lock()
+	delete(tempLive, filepath.Clean(dir))
+	stopHeartbeatTemp()
+	tempLiveMu.Unlock()
+
+	return err
+}
+
+// openTempRoot creates the temporary directory root, or checks that the
+// existing one is a directory of this user that nobody else can write to,
+// not a symbolic link planted by another user of the host.
+func openTempRoot() error {
+	if err := os.Mkdir(tempRoot, 0700); err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	info, err := os.Lstat(tempRoot)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", tempRoot)
+	}
+
+	// Only the owner can change the mode of a directory.
+	if info.Mode().Perm() != 0700 {
+		if err := os.Chmod(tempRoot, 0700); err != nil {
+			return fmt.Errorf("%s is not owned by this user: %v", tempRoot, err)
+		}
+	}
+	after, err := os.Lstat(tempRoot)
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(info, after) || after.Mode().Perm() != 0700 {
+		return fmt.Errorf("%s changed while being checked", tempRoot)
+	}
+
+	return nil
+}
+
+// heartbeatTemp touches the temporary directories of the process for as
+// long as they exist, so that no PurgeTemp takes them for orphans, until
+// stop is closed.
+func heartbeatTemp(stop chan struct{}) {
+	ticker := time.NewTicker(tempHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+
+		tempLiveMu.Lock()
+		for dir := range tempLive {
+			if err := os.Chtimes(dir, now, now); os.IsNotExist(err) {
+				delete(tempLive, dir)
+			}
+		}
+		stopHeartbeatTemp()
+		tempLiveMu.Unlock()
+	}
+}
+
+// stopHeartbeatTemp stops the heartbeat once the process has no temporary
+// directory left. tempLiveMu must be held.
+func stopHeartbeatTemp() {
+	if len(tempLive) == 0 && tempBeatStop != nil {
+		close(tempBeatStop)
+		tempBeatStop = nil
+	}
+}
+
+// PurgeTemp removes the temporary repositories left behind by runs that
+// crashed or were killed. Those of running processes, this one included,
+// are kept.
+func PurgeTemp() error {
+	if _, err := os.Lstat(tempRoot); os.IsNotExist(err) {
+		return nil
+	}
+	if err := openTempRoot(); err != nil {
+		return err
+	}
+
+	entries, err := ioutil.ReadDir(tempRoot)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, entry := range entries {
+		if !entry.IsDir() || time.Since(entry.ModTime()) < tempOrphanAge {
+			continue
+		}
+
+		dir := filepath.Join(tempRoot, entry.Name())
+		tempLiveMu.Lock()
+		live := tempLive[dir]
+		tempLiveMu.Unlock()
+		if live {
+			continue
+		}
+
+		if err := os.RemoveAll(dir); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
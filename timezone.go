@@ -0,0 +1,121 @@
+package sourcegit
+
+import (
+	"fmt"
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+	"strconv"
+)
+
+// A timezone used by at least this share of the commits is part of the norm
+// of the repository, and offsets further than tzAnomalyDistance from every
+// timezone of the norm are anomalous.
+const (
+	tzNormShare       = 0.05
+	tzAnomalyDistance = 3 * 3600
+)
+
+// timezoneStats counts the author timezone offsets (in seconds east of UTC)
+// of the commits walked.
+type timezoneStats struct {
+	commits int
+	offsets map[int]int
+}
+
+func (ts *timezoneStats) add(offset int) {
+	if ts.offsets == nil {
+		ts.offsets = make(map[int]int)
+	}
+	ts.commits++
+	ts.offsets[offset]++
+}
+
+// anomalous reports whether offset is far from every timezone of the norm.
+// The most used timezone is always part of the norm.
+func (ts *timezoneStats) anomalous(offset int) bool {
+	best, bestCount := 0, -1
+	for o, count := range ts.offsets {
+		if count > bestCount || count == bestCount && o < best {
+			best, bestCount = o, count
+		}
+	}
+	if bestCount < 0 {
+		return false
+	}
+
+	for o, count := range ts.offsets {
+		if o != best && float64(count) < tzNormShare*float64(ts.commits) {
+			continue
+		}
+		if tzDistance(o, offset) <= tzAnomalyDistance {
+			return false
+		}
+	}
+
+	return true
+}
+
+// tzDistance is the distance between two offsets around the clock.
+func tzDistance(a, b int) int {
+	d := a - b
+	if d < 0 {
+		d = -d
+	}
+	if d > 12*3600 {
+		d = 24*3600 - d
+	}
+
+	return d
+}
+
+// authorTimezone returns the author timezone offset of commit, in seconds.
+func authorTimezone(commit *git.Commit) int {
+	_, offset := commit.Author().When.Zone()
+	return offset
+}
+
+// formatTimezone formats an offset like git does ("+0200").
+func formatTimezone(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+
+	return fmt.Sprintf("%c%02d%02d", sign, offset/3600, offset%3600/60)
+}
+
+// parseTimezone parses an offset formatted by formatTimezone.
+func parseTimezone(s string) (int, bool) {
+	if len(s) != 5 || (s[0] != '+' && s[0] != '-') {
+		return 0, false
+	}
+	hours, err1 := strconv.Atoi(s[1:3])
+	minutes, err2 := strconv.Atoi(s[3:5])
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+
+	offset := hours*3600 + minutes*60
+	if s[0] == '-' {
+		offset = -offset
+	}
+
+	return offset, true
+}
+
+// annotateTimezones flags the objects of commits authored in a timezone
+// that stands out from the norm of the repository ("tz-anomaly").
+func (c *objectCollector) annotateTimezones() {
+	for i := range c.objects {
+		tz, err := c.objects[i].GetMetadata("author-tz")
+		if err != nil {
+			continue
+		}
+		offset, ok := parseTimezone(tz)
+		if !ok {
+			continue
+		}
+
+		c.objects[i].SetMetadata("tz-anomaly", strconv.FormatBool(c.timezones.anomalous(offset)), models.MetadataAttributes{})
+	}
+}
@@ -0,0 +1,59 @@
+package sourcegit
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// caCertificateCheck returns a certificate check callback that also trusts
+// the certificate authorities of the PEM bundle at path, for servers signed
+// by an internal CA. SSH host keys are accepted as before.
+func caCertificateCheck(path string) (git.CertificateCheckCallback, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("ca-bundle %s: no certificate found", path)
+	}
+
+	return func(cert *git.Certificate, valid bool, hostname string) git.CallbackResult {
+		if valid || cert.Kind != git.CertificateX509 || cert.X509 == nil {
+			return callbackOk
+		}
+
+		_, err := cert.X509.Verify(x509.VerifyOptions{
+			DNSName: hostname,
+			Roots:   roots,
+		})
+		if err != nil {
+			return git.CallbackRejectCertificate
+		}
+
+		return callbackOk
+	}, nil
+}
+
+// tlsCloneOptions returns the options for the git CLI to use ca-bundle and
+// the client certificate. libgit2 cannot present client certificates, so
+// remotes requiring them are cloned with the git CLI.
+func tlsCloneOptions(opt SourceGitLoadOptions) []string {
+	var options []string
+
+	if opt.CaBundle != "" {
+		options = append(options, "-c", "http.sslCAInfo="+opt.CaBundle)
+	}
+	if opt.ClientCert != "" {
+		options = append(options, "-c", "http.sslCert="+opt.ClientCert)
+	}
+	if opt.ClientKey != "" {
+		options = append(options, "-c", "http.sslKey="+opt.ClientKey)
+	}
+
+	return options
+}
@@ -0,0 +1,32 @@
+package sourcegit
+
+import (
+	"strings"
+
+	"github.com/apuigsech/seekret-source-git/internal/git"
+)
+
+// trustedCommit tells whether both the author and the committer of commit
+// have an email in one of the trusted domains (or their subdomains), after
+// mailmap canonicalization.
+func trustedCommit(commit *git.Commit, domains []string, mm *mailmap) bool {
+	return trustedEmail(mm.signature(commit.Author()).Email, domains) &&
+		trustedEmail(mm.signature(commit.Committer()).Email, domains)
+}
+
+func trustedEmail(email string, domains []string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	host := strings.ToLower(email[at+1:])
+
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "@"))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,113 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret"
+	"github.com/apuigsech/seekret/models"
+	"sync"
+	"time"
+)
+
+// watchRefspec is what Watch fetches unless fetch-refs says otherwise.
+const watchRefspec = "+refs/heads/*:refs/remotes/origin/*"
+
+// WatchEvent is sent by a Watcher for every change bringing new objects, and
+// for every failure.
+type WatchEvent struct {
+	Objects []models.Object
+	Report  *LoadReport
+	Err     error
+}
+
+// Watcher follows a source for new commits (see Watch and WatchLocal).
+// Events are sent on Events until Close, which closes the channel.
+type Watcher struct {
+	Events <-chan WatchEvent
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// Watch keeps fetching source every interval and sends the objects of the
+// commits each fetch brings in, loaded with the given options, as
+// WatchEvents. Commits already there when the watch starts are not sent.
+// Remotes are cloned once, into a temporary directory removed on Close;
+// local repositories are fetched from their fetch-remote (default
+// "origin"). Fetched refspecs are fetch-refs, by default every branch.
+func (s *SourceGit) Watch(source string, opta seekret.LoadOptions, interval time.Duration) (*Watcher, error) {
+	source = localSource(source)
+
+	path := source
+	tmpdir := ""
+	if _, remote := normalizeGitUri(source); remote {
+		repo, err := openGitRepo(source, prepareGitLoadOptions(opta))
+		if err != nil {
+			return nil, err
+		}
+		path = repo.Workdir()
+		if repo.IsBare() || path == "" {
+			path = repo.Path()
+		}
+		tmpdir = path
+		view := repo.Path()
+		repo.Free()
+		releasePartialView(view)
+	}
+
+	watchOpts := make(seekret.LoadOptions, len(opta)+1)
+	for k, v := range opta {
+		watchOpts[k] = v
+	}
+	if _, ok := stringListOption(watchOpts["fetch-refs"]); !ok {
+		watchOpts["fetch-refs"] = []string{watchRefspec}
+	}
+
+	events := make(chan WatchEvent)
+	w := &Watcher{
+		Events: events,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+		defer close(events)
+		if tmpdir != "" {
+			defer removeTempDir(tmpdir)
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+			}
+
+			objectList, report, err := s.LoadObjectsWithReport(path, watchOpts)
+			if err == nil && len(objectList) == 0 {
+				continue
+			}
+
+			select {
+			case events <- WatchEvent{Objects: objectList, Report: report, Err: err}:
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+// Close stops the watch, waiting for a load in progress to end.
+func (w *Watcher) Close() error {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package sourcegit
+
+import (
+	"crypto/sha1"
+	"github.com/apuigsech/seekret"
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+	"github.com/fsnotify/fsnotify"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// watchLocalDelay lets git finish a burst of writes (lock files, reflogs,
+// packed-refs) before the repository is looked at.
+const watchLocalDelay = 250 * time.Millisecond
+
+// WatchLocal watches the local repository at source through the file system
+// notifications of the platform (inotify, FSEvents, ...) and sends, as soon
+// as they appear, the objects of new commits on any ref, loaded with the
+// given options, and the staged files whose content changed. Commits and
+// staged files already there when the watch starts are not sent.
+func (s *SourceGit) WatchLocal(source string, opta seekret.LoadOptions) (*Watcher, error) {
+	source = localSource(source)
+
+	repo, err := openGitRepoLocal(source, prepareGitLoadOptions(opta))
+	if err != nil {
+		return nil, err
+	}
+	gitDir := repo.Path()
+	tips, err := refTipsByName(repo)
+	repo.Free()
+	if err != nil {
+		return nil, err
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// fsnotify is not recursive: every directory of refs is watched.
+	err = filepath.Walk(gitDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if p != gitDir && !strings.HasPrefix(p, filepath.Join(gitDir, "refs")) {
+			return filepath.SkipDir
+		}
+		return fsw.Add(p)
+	})
+	if err != nil {
+		fsw.Close()
+		return nil, err
+	}
+
+	lw := &localWatch{
+		source: s,
+		path:   source,
+		opta:   opta,
+		tips:   tips,
+		staged: make(map[string]bool),
+	}
+	// What is staged now is not news.
+	lw.stagedObjects()
+
+	events := make(chan WatchEvent)
+	w := &Watcher{
+		Events: events,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+		defer close(events)
+		defer fsw.Close()
+
+		timer := time.NewTimer(watchLocalDelay)
+		timer.Stop()
+		refsChanged, indexChanged := false, false
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			case err := <-fsw.Errors:
+				select {
+				case events <- WatchEvent{Err: err}:
+				case <-w.stop:
+					return
+				}
+				continue
+			case e := <-fsw.Events:
+				if strings.HasSuffix(e.Name, ".lock") {
+					continue
+				}
+				if e.Op&fsnotify.Create != 0 {
+					if info, err := os.Stat(e.Name); err == nil && info.IsDir() {
+						fsw.Add(e.Name)
+					}
+				}
+				if filepath.Base(e.Name) == "index" && filepath.Dir(e.Name) == gitDir {
+					indexChanged = true
+				} else {
+					refsChanged = true
+				}
+				timer.Reset(watchLocalDelay)
+				continue
+			case <-timer.C:
+			}
+
+			event := WatchEvent{Report: &LoadReport{}}
+			if refsChanged {
+				event.Objects, event.Report, event.Err = lw.newCommits()
+			}
+			if indexChanged && event.Err == nil {
+				event.Objects = append(event.Objects, lw.stagedObjects()...)
+			}
+			refsChanged, indexChanged = false, false
+
+			if event.Err == nil && len(event.Objects) == 0 {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+// localWatch is the state of a WatchLocal between notifications.
+type localWatch struct {
+	source *SourceGit
+	path   string
+	opta   seekret.LoadOptions
+	// Commit of every ref, and staged contents already sent.
+	tips   map[string]string
+	staged map[string]bool
+}
+
+// newCommits loads the commits of the refs that moved since last time, and
+// not reachable from where any ref was.
+func (lw *localWatch) newCommits() ([]models.Object, *LoadReport, error) {
+	repo, err := openGitRepoLocal(lw.path, prepareGitLoadOptions(lw.opta))
+	if err != nil {
+		return nil, nil, err
+	}
+	tips, err := refTipsByName(repo)
+	repo.Free()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var known []string
+	for _, tip := range lw.tips {
+		known = append(known, tip)
+	}
+
+	var objectList []models.Object
+	report := &LoadReport{}
+	for name, tip := range tips {
+		if lw.tips[name] == tip {
+			continue
+		}
+
+		refOpts := make(seekret.LoadOptions, len(lw.opta)+2)
+		for k, v := range lw.opta {
+			refOpts[k] = v
+		}
+		refOpts["revision-range"] = tip
+		refOpts["exclude-commits"] = known
+
+		refObjects, refReport, err := lw.source.LoadObjectsWithReport(lw.path, refOpts)
+		if err != nil {
+			return objectList, report, err
+		}
+		objectList = append(objectList, refObjects...)
+		report.merge(refReport)
+	}
+	lw.tips = tips
+
+	return objectList, report, nil
+}
+
+// stagedObjects returns the staged files whose content was not sent yet.
+// Failures are left for the next change of the index.
+func (lw *localWatch) stagedObjects() []models.Object {
+	stagedOpts := make(seekret.LoadOptions, len(lw.opta)+6)
+	for k, v := range lw.opta {
+		stagedOpts[k] = v
+	}
+	for _, k := range []string{"commit-files", "commit-messages", "deleted-files", "commit-metadata", "commit-diffs"} {
+		stagedOpts[k] = false
+	}
+	stagedOpts["staged-files"] = true
+
+	objectList, err := lw.source.LoadObjects(lw.path, stagedOpts)
+	if err != nil {
+		return nil
+	}
+
+	var fresh []models.Object
+	for _, o := range objectList {
+		sum := sha1.Sum(o.Content)
+		key := o.Name + "\x00" + string(sum[:])
+		if lw.staged[key] {
+			continue
+		}
+		lw.staged[key] = true
+		fresh = append(fresh, o)
+	}
+
+	return fresh
+}
+
+// refTipsByName returns the commit every ref of repo points to.
+func refTipsByName(repo *git.Repository) (map[string]string, error) {
+	iter, err := repo.NewReferenceIterator()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Free()
+
+	tips := make(map[string]string)
+	for {
+		ref, err := iter.Next()
+		if err != nil {
+			if isIterOver(err) {
+				break
+			}
+			return nil, err
+		}
+
+		if ref.Type() == git.ReferenceOid {
+			if obj, err := ref.Peel(git.ObjectCommit); err == nil {
+				tips[ref.Name()] = obj.Id().String()
+				obj.Free()
+			}
+		}
+		ref.Free()
+	}
+
+	return tips, nil
+}
@@ -0,0 +1,80 @@
+package sourcegit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/apuigsech/seekret"
+	"strings"
+)
+
+const zeroCommit = "0000000000000000000000000000000000000000"
+
+// ErrNothingToScan is returned by WebhookScan for pushes that add no
+// commits, such as branch deletions.
+var ErrNothingToScan = errors.New("push adds no commits")
+
+// webhookPush holds the fields shared by GitHub and GitLab push events.
+type webhookPush struct {
+	Ref    string `json:"ref"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+
+	// GitHub.
+	Repository struct {
+		CloneUrl string `json:"clone_url"`
+		// GitLab.
+		GitHttpUrl string `json:"git_http_url"`
+	} `json:"repository"`
+	// GitLab.
+	Project struct {
+		GitHttpUrl string `json:"git_http_url"`
+	} `json:"project"`
+}
+
+// WebhookScan translates the payload of a GitHub or GitLab push webhook into
+// the source and load options that scan exactly the pushed commits:
+//
+//	source, opts, err := sourcegit.WebhookScan(payload)
+//	objects, err := sourcegit.SourceTypeGit.LoadObjects(source, opts)
+//
+// Commit files and messages are scanned; the options can be adjusted before
+// loading. A push creating a branch, or forcing one to a history the
+// previous tip is no longer in, scans the commits not on any other ref.
+func WebhookScan(payload []byte) (string, seekret.LoadOptions, error) {
+	var push webhookPush
+	if err := json.Unmarshal(payload, &push); err != nil {
+		return "", nil, err
+	}
+
+	source := push.Repository.CloneUrl
+	if source == "" {
+		source = push.Project.GitHttpUrl
+	}
+	if source == "" {
+		source = push.Repository.GitHttpUrl
+	}
+	if source == "" || push.After == "" {
+		return "", nil, fmt.Errorf("not a push webhook payload")
+	}
+
+	if push.After == zeroCommit {
+		return "", nil, ErrNothingToScan
+	}
+	if !strings.HasPrefix(push.Ref, "refs/heads/") && !strings.HasPrefix(push.Ref, "refs/tags/") {
+		return "", nil, fmt.Errorf("unsupported ref %q", push.Ref)
+	}
+
+	before := push.Before
+	if before == "" {
+		before = zeroCommit
+	}
+
+	opts := seekret.LoadOptions{
+		"commit-files":    true,
+		"commit-messages": true,
+		"push-update":     fmt.Sprintf("%s %s %s", before, push.After, push.Ref),
+	}
+
+	return source, opts, nil
+}
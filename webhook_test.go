@@ -0,0 +1,82 @@
+package sourcegit
+
+import (
+	"testing"
+)
+
+func TestWebhookScan(t *testing.T) {
+	const (
+		before = "1111111111111111111111111111111111111111"
+		after  = "2222222222222222222222222222222222222222"
+	)
+
+	tests := []struct {
+		name       string
+		payload    string
+		wantSource string
+		wantUpdate string
+		wantErr    bool
+		// The error wanted, when a specific one.
+		wantErrIs error
+	}{
+		{
+			name:       "github",
+			payload:    `{"ref": "refs/heads/main", "before": "` + before + `", "after": "` + after + `", "repository": {"clone_url": "https://github.com/o/r.git"}}`,
+			wantSource: "https://github.com/o/r.git",
+			wantUpdate: before + " " + after + " refs/heads/main",
+		},
+		{
+			name:       "gitlab",
+			payload:    `{"ref": "refs/tags/v1", "before": "` + before + `", "after": "` + after + `", "project": {"git_http_url": "https://gitlab.com/o/r.git"}}`,
+			wantSource: "https://gitlab.com/o/r.git",
+			wantUpdate: before + " " + after + " refs/tags/v1",
+		},
+		{
+			name:       "new branch",
+			payload:    `{"ref": "refs/heads/topic", "after": "` + after + `", "repository": {"git_http_url": "https://gitlab.com/o/r.git"}}`,
+			wantSource: "https://gitlab.com/o/r.git",
+			wantUpdate: zeroCommit + " " + after + " refs/heads/topic",
+		},
+		{
+			name:      "deleted branch",
+			payload:   `{"ref": "refs/heads/topic", "before": "` + before + `", "after": "` + zeroCommit + `", "repository": {"clone_url": "https://github.com/o/r.git"}}`,
+			wantErr:   true,
+			wantErrIs: ErrNothingToScan,
+		},
+		{
+			name:    "unsupported ref",
+			payload: `{"ref": "refs/notes/commits", "before": "` + before + `", "after": "` + after + `", "repository": {"clone_url": "https://github.com/o/r.git"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "not a push",
+			payload: `{"action": "opened", "repository": {"clone_url": "https://github.com/o/r.git"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "not json",
+			payload: `ref=refs/heads/main`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		source, opts, err := WebhookScan([]byte(test.payload))
+		if test.wantErr {
+			if err == nil || test.wantErrIs != nil && err != test.wantErrIs {
+				t.Errorf("%s: error %v, want %v", test.name, err, test.wantErrIs)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if source != test.wantSource {
+			t.Errorf("%s: source %q, want %q", test.name, source, test.wantSource)
+		}
+		if opts["push-update"] != test.wantUpdate {
+			t.Errorf("%s: push-update %q, want %q", test.name, opts["push-update"], test.wantUpdate)
+		}
+	}
+}
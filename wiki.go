@@ -0,0 +1,51 @@
+package sourcegit
+
+import (
+	"fmt"
+	"github.com/apuigsech/seekret"
+	"github.com/apuigsech/seekret/models"
+	"strings"
+)
+
+// wikiSource returns the URL of the wiki repository of a GitHub or GitLab
+// project given by its remote URL.
+func wikiSource(source string) (string, bool) {
+	gitUri, remote := normalizeGitUri(source)
+	if !remote {
+		return "", false
+	}
+
+	return strings.TrimSuffix(gitUri, ".git") + ".wiki.git", true
+}
+
+// loadWithWiki scans the project repository and then its wiki. The objects
+// of the wiki carry "wiki" metadata. A project without a wiki (or without
+// access to it) only gets a warning in the report.
+func (s *SourceGit) loadWithWiki(source string, wiki string, opta seekret.LoadOptions) ([]models.Object, *LoadReport, error) {
+	mainOpts := make(seekret.LoadOptions, len(opta))
+	for k, v := range opta {
+		mainOpts[k] = v
+	}
+	mainOpts["include-wiki"] = false
+	// Streamed by load once merged with the wiki.
+	delete(mainOpts, "stream-file")
+
+	objectList, report, err := s.LoadObjectsWithReport(source, mainOpts)
+	if err != nil {
+		return objectList, report, err
+	}
+
+	wikiOpts := subSourceOptions(mainOpts)
+	wikiObjects, wikiReport, err := s.LoadObjectsWithReport(wiki, wikiOpts)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("wiki %s: %v", wiki, err))
+		return objectList, report, nil
+	}
+	report.merge(wikiReport)
+
+	for i := range wikiObjects {
+		wikiObjects[i].SetMetadata("wiki", "true", models.MetadataAttributes{})
+	}
+
+	return append(objectList, wikiObjects...), report, nil
+}
@@ -0,0 +1,103 @@
+package sourcegit
+
+import (
+	"github.com/apuigsech/seekret-source-git/internal/git"
+	"github.com/apuigsech/seekret/models"
+	"io/ioutil"
+)
+
+// objectsFromWorktreeChanges emits what a commit made now would and would
+// not include, as three object types so that pre-commit hooks can apply a
+// policy to each: the lines staged (index against HEAD, "staged-change"),
+// the lines changed but not staged (working directory against index,
+// "unstaged-change") and the content of untracked files ("untracked-file").
+// Bare repositories have none of them.
+func objectsFromWorktreeChanges(repo *git.Repository, opt SourceGitLoadOptions, collector *objectCollector) ([]models.Object, error) {
+	if repo.IsBare() {
+		return nil, nil
+	}
+
+	index, err := repo.Index()
+	if err != nil {
+		return nil, err
+	}
+	defer index.Free()
+
+	keep := func(p string) bool {
+		return !collector.excludedPath(p) && (len(opt.Pathspec) == 0 || matchPathspec(p, opt.Pathspec))
+	}
+
+	diffOpts, err := git.DefaultDiffOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	// An unborn HEAD has no tree: everything in the index is staged.
+	var headTree *git.Tree
+	if head, err := repo.Head(); err == nil {
+		commit, err := repo.LookupCommit(head.Target())
+		head.Free()
+		if err != nil {
+			return nil, err
+		}
+		headTree, err = commit.Tree()
+		commit.Free()
+		if err != nil {
+			return nil, err
+		}
+		defer headTree.Free()
+	}
+
+	staged, err := repo.DiffTreeToIndex(headTree, index, &diffOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer staged.Free()
+
+	objectList, err := diffAddedLines(staged, "staged-change", keep)
+	if err != nil {
+		return nil, err
+	}
+
+	diffOpts.Flags |= git.DiffIncludeUntracked | git.DiffRecurseUntracked
+	unstaged, err := repo.DiffIndexToWorkdir(index, &diffOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer unstaged.Free()
+
+	unstagedObjects, err := diffAddedLines(unstaged, "unstaged-change", keep)
+	if err != nil {
+		return nil, err
+	}
+	objectList = append(objectList, unstagedObjects...)
+
+	count, err := unstaged.NumDeltas()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < count; i++ {
+		delta, err := unstaged.GetDelta(i)
+		if err != nil {
+			return nil, err
+		}
+		if delta.Status != git.DeltaUntracked || !keep(delta.NewFile.Path) {
+			continue
+		}
+
+		// Symbolic links are not followed out of the working directory.
+		p, err := checkoutPath(repo.Workdir(), delta.NewFile.Path)
+		if err != nil {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			// Gone since the diff, or a directory: nothing to scan.
+			continue
+		}
+		objectList = append(objectList, *models.NewObject(delta.NewFile.Path, Type, "untracked-file", content))
+	}
+
+	return objectList, nil
+}